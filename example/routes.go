@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"github.com/Yuki-J1/wailsrouter/pkg/app/server"
 	"github.com/Yuki-J1/wailsrouter/pkg/route"
+	"net/http"
 	"time"
 )
 
@@ -22,6 +23,7 @@ func main() {
 	requestCtx := de.NewContext()
 	requestCtx.Path = []byte("/user/YKJ")
 	de.Serve(context.Background(), requestCtx)
+	fmt.Println(requestCtx.Response.StatusCode, string(requestCtx.Response.Body))
 
 	time.Sleep(100 * time.Second)
 }
@@ -29,7 +31,6 @@ func main() {
 func HandlerTest1(c context.Context, ctx *server.RequestContext) {
 	val, ok := ctx.Params.Get("name")
 	if ok == true {
-		fmt.Print("handlerTest1")
-		fmt.Print(val)
+		ctx.String(http.StatusOK, "handlerTest1 %s", val)
 	}
 }
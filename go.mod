@@ -2,9 +2,15 @@ module github.com/Yuki-J1/wailsrouter
 
 go 1.21.0
 
-require github.com/cloudwego/hertz v0.7.2
+require (
+	github.com/cloudwego/hertz v0.7.2
+	github.com/fxamacker/cbor/v2 v2.7.0
+	github.com/vmihailenco/msgpack/v5 v5.4.1
+)
 
 require (
 	github.com/bytedance/gopkg v0.0.0-20220413063733-65bf48ffb3a7 // indirect
 	github.com/cloudwego/netpoll v0.5.0 // indirect
+	github.com/vmihailenco/tagparser/v2 v2.0.0 // indirect
+	github.com/x448/float16 v0.8.4 // indirect
 )
@@ -235,6 +235,30 @@ func AppendQuotedArg(dst, src []byte) []byte {
 	return dst
 }
 
+// AppendUnquotedArg appends url-decoded src to dst and returns appended dst.
+// '+' is decoded to ' ', invalid %-sequences are kept as is.
+func AppendUnquotedArg(dst, src []byte) []byte {
+	for i := 0; i < len(src); i++ {
+		c := src[i]
+		switch {
+		case c == '+':
+			dst = append(dst, ' ')
+		case c == '%' && i+2 < len(src):
+			x2 := Hex2intTable[src[i+2]]
+			x1 := Hex2intTable[src[i+1]]
+			if x1 == 16 || x2 == 16 {
+				dst = append(dst, '%')
+			} else {
+				dst = append(dst, x1<<4|x2)
+				i += 2
+			}
+		default:
+			dst = append(dst, c)
+		}
+	}
+	return dst
+}
+
 // ParseHTTPDate parses HTTP-compliant (RFC1123) date.
 func ParseHTTPDate(date []byte) (time.Time, error) {
 	return time.Parse(time.RFC1123, B2s(date))
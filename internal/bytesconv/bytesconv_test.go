@@ -163,6 +163,27 @@ func TestAppendQuotedArg(t *testing.T) {
 	assert.DeepEqual(t, expect, res)
 }
 
+func TestAppendUnquotedArg(t *testing.T) {
+	t.Parallel()
+
+	// Sync with url.QueryUnescape
+	allcases := make([]byte, 256)
+	for i := 0; i < 256; i++ {
+		allcases[i] = byte(i)
+	}
+	expect := B2s(allcases)
+	res := B2s(AppendUnquotedArg(nil, AppendQuotedArg(nil, allcases)))
+	assert.DeepEqual(t, expect, res)
+
+	for _, src := range []string{"a+b", "%E4%BD%A0%e5%a5%bd", "100%", "%zz", "%4"} {
+		expect, _ := url.QueryUnescape(src)
+		if expect == "" {
+			expect = src
+		}
+		assert.DeepEqual(t, expect, string(AppendUnquotedArg(nil, []byte(src))))
+	}
+}
+
 func TestParseHTTPDate(t *testing.T) {
 	t.Parallel()
 
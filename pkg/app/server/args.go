@@ -0,0 +1,200 @@
+package server
+
+import (
+	"bytes"
+
+	"github.com/Yuki-J1/wailsrouter/internal/bytesconv"
+)
+
+// argsKV 一个查询参数
+type argsKV struct {
+	key     []byte
+	value   []byte
+	noValue bool   // 参数没有 = eg: ?debug
+	buf     []byte // 需要反转义时存放结果，解析下一个查询字符串时复用
+}
+
+// Args 解析后的查询参数 eg: q=go&page=2&tag=a&tag=b
+// 不需要反转义的键和值直接引用原始查询字符串，不会复制
+// 因此在 Args 被重置或重新解析之前，原始查询字符串不能被修改
+type Args struct {
+	args []argsKV
+}
+
+// Parse 解析查询字符串，之前的参数会被清空
+func (a *Args) Parse(query []byte) {
+	a.Reset()
+	for len(query) > 0 {
+		var pair []byte
+		if i := bytes.IndexByte(query, '&'); i != -1 {
+			pair, query = query[:i], query[i+1:]
+		} else {
+			pair, query = query, nil
+		}
+		if len(pair) == 0 {
+			continue
+		}
+
+		kv := a.alloc()
+		key, value, found := bytes.Cut(pair, []byte{'='})
+		kv.noValue = !found
+		kv.buf = kv.buf[:0]
+		k := -1
+		if needUnquote(key) {
+			kv.buf = bytesconv.AppendUnquotedArg(kv.buf, key)
+			k = len(kv.buf)
+		}
+		if needUnquote(value) {
+			start := len(kv.buf)
+			kv.buf = bytesconv.AppendUnquotedArg(kv.buf, value)
+			value = kv.buf[start:]
+		}
+		// buf 可能在追加 value 时扩容，所以最后再从 buf 中取 key
+		if k != -1 {
+			key = kv.buf[:k]
+		}
+		kv.key, kv.value = key, value
+	}
+}
+
+// alloc 追加一个参数，复用之前解析时的 buf
+func (a *Args) alloc() *argsKV {
+	if n := len(a.args); n < cap(a.args) {
+		a.args = a.args[:n+1]
+	} else {
+		a.args = append(a.args, argsKV{})
+	}
+	return &a.args[len(a.args)-1]
+}
+
+// needUnquote 是否包含需要反转义的字符
+func needUnquote(b []byte) bool {
+	return bytes.IndexByte(b, '%') != -1 || bytes.IndexByte(b, '+') != -1
+}
+
+// Reset 清空所有参数
+func (a *Args) Reset() {
+	for i := range a.args {
+		a.args[i].key, a.args[i].value = nil, nil
+	}
+	a.args = a.args[:0]
+}
+
+// Len 参数个数，同名参数分别计数
+func (a *Args) Len() int {
+	return len(a.args)
+}
+
+// Has 是否存在名为 key 的参数
+func (a *Args) Has(key string) bool {
+	for i := range a.args {
+		if string(a.args[i].key) == key {
+			return true
+		}
+	}
+	return false
+}
+
+// Peek 返回第一个名为 key 的参数值，不存在时返回 nil
+// 返回值在 Args 被重置之前有效
+func (a *Args) Peek(key string) []byte {
+	for i := range a.args {
+		if string(a.args[i].key) == key {
+			return a.args[i].value
+		}
+	}
+	return nil
+}
+
+// PeekAll 返回所有名为 key 的参数值
+func (a *Args) PeekAll(key string) (values [][]byte) {
+	for i := range a.args {
+		if string(a.args[i].key) == key {
+			values = append(values, a.args[i].value)
+		}
+	}
+	return
+}
+
+// VisitAll 按顺序访问所有参数
+func (a *Args) VisitAll(f func(key, value []byte)) {
+	for i := range a.args {
+		f(a.args[i].key, a.args[i].value)
+	}
+}
+
+// AppendBytes 将参数编码为查询字符串追加到 dst
+func (a *Args) AppendBytes(dst []byte) []byte {
+	for i := range a.args {
+		kv := &a.args[i]
+		if i > 0 {
+			dst = append(dst, '&')
+		}
+		dst = bytesconv.AppendQuotedArg(dst, kv.key)
+		if !kv.noValue {
+			dst = append(dst, '=')
+			dst = bytesconv.AppendQuotedArg(dst, kv.value)
+		}
+	}
+	return dst
+}
+
+// String 返回编码后的查询字符串
+func (a *Args) String() string {
+	return string(a.AppendBytes(nil))
+}
+
+// SetQueryString 设置查询字符串 (不包含 ?)，之前解析的查询参数失效
+// query 会被复制到请求上下文复用的缓冲区中，之后修改 query 不会影响查询参数
+func (ctx *RequestContext) SetQueryString(query []byte) {
+	ctx.queryBuf = append(ctx.queryBuf[:0], query...)
+	ctx.Request.QueryString = ctx.queryBuf
+	ctx.queryParsed = false
+}
+
+// QueryArgs 返回解析后的查询参数，第一次调用时解析 Request.QueryString
+func (ctx *RequestContext) QueryArgs() *Args {
+	if !ctx.queryParsed {
+		ctx.queryArgs.Parse(ctx.Request.QueryString)
+		ctx.queryParsed = true
+	}
+	return &ctx.queryArgs
+}
+
+// GetQuery 返回名为 key 的查询参数值，以及该参数是否存在
+// eg: /search?q=go&empty=  GetQuery("q") 返回 "go", true  GetQuery("empty") 返回 "", true
+func (ctx *RequestContext) GetQuery(key string) (string, bool) {
+	args := ctx.QueryArgs()
+	if !args.Has(key) {
+		return "", false
+	}
+	return string(args.Peek(key)), true
+}
+
+// Query 返回名为 key 的查询参数值，不存在时返回空字符串
+func (ctx *RequestContext) Query(key string) string {
+	value, _ := ctx.GetQuery(key)
+	return value
+}
+
+// DefaultQuery 返回名为 key 的查询参数值，不存在时返回 defaultValue
+func (ctx *RequestContext) DefaultQuery(key, defaultValue string) string {
+	if value, ok := ctx.GetQuery(key); ok {
+		return value
+	}
+	return defaultValue
+}
+
+// QueryArray 返回所有名为 key 的查询参数值
+// eg: /search?tag=a&tag=b  QueryArray("tag") 返回 ["a", "b"]
+func (ctx *RequestContext) QueryArray(key string) []string {
+	values := ctx.QueryArgs().PeekAll(key)
+	if values == nil {
+		return nil
+	}
+	array := make([]string, len(values))
+	for i, v := range values {
+		array[i] = string(v)
+	}
+	return array
+}
@@ -0,0 +1,60 @@
+package server
+
+import (
+	"github.com/cloudwego/hertz/pkg/common/test/assert"
+	"testing"
+)
+
+func TestArgs_Parse(t *testing.T) {
+	var args Args
+	query := []byte("q=go+lang&page=2&tag=a&&tag=b&debug&empty=&%E4%BD%A0=%E5%A5%BD")
+	args.Parse(query)
+
+	assert.DeepEqual(t, 7, args.Len())
+	assert.DeepEqual(t, "go lang", string(args.Peek("q")))
+	assert.DeepEqual(t, "2", string(args.Peek("page")))
+	assert.DeepEqual(t, [][]byte{[]byte("a"), []byte("b")}, args.PeekAll("tag"))
+	assert.True(t, args.Has("debug"))
+	assert.True(t, args.Has("empty"))
+	assert.DeepEqual(t, 0, len(args.Peek("empty")))
+	assert.DeepEqual(t, "好", string(args.Peek("你")))
+	assert.False(t, args.Has("missing"))
+	assert.Nil(t, args.Peek("missing"))
+
+	// 不需要反转义的值直接引用原始查询字符串
+	page := args.Peek("page")
+	assert.True(t, &page[0] == &query[15])
+
+	var keys []string
+	args.VisitAll(func(key, value []byte) {
+		keys = append(keys, string(key))
+	})
+	assert.DeepEqual(t, []string{"q", "page", "tag", "tag", "debug", "empty", "你"}, keys)
+	assert.DeepEqual(t, "q=go+lang&page=2&tag=a&tag=b&debug&empty=&%E4%BD%A0=%E5%A5%BD", args.String())
+
+	// 重新解析时复用之前的参数
+	args.Parse([]byte("a=1"))
+	assert.DeepEqual(t, 1, args.Len())
+	assert.DeepEqual(t, "1", string(args.Peek("a")))
+	assert.False(t, args.Has("q"))
+}
+
+func TestRequestContext_Query(t *testing.T) {
+	ctx := NewContext(0)
+	ctx.SetQueryString([]byte("q=go&tag=a&tag=b&empty="))
+
+	value, ok := ctx.GetQuery("empty")
+	assert.True(t, ok)
+	assert.DeepEqual(t, "", value)
+	assert.DeepEqual(t, "go", ctx.Query("q"))
+	assert.DeepEqual(t, "", ctx.Query("missing"))
+	assert.DeepEqual(t, "1", ctx.DefaultQuery("page", "1"))
+	assert.DeepEqual(t, "", ctx.DefaultQuery("empty", "1"))
+	assert.DeepEqual(t, []string{"a", "b"}, ctx.QueryArray("tag"))
+	assert.Nil(t, ctx.QueryArray("missing"))
+
+	cp := ctx.Copy()
+	ctx.Reset()
+	assert.DeepEqual(t, "", ctx.Query("q"))
+	assert.DeepEqual(t, "go", cp.Query("q"))
+}
@@ -0,0 +1,381 @@
+package server
+
+import (
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 绑定时值的来源，同时也是结构体标签的名称
+const (
+	bindSourcePath    = "path"
+	bindSourceQuery   = "query"
+	bindSourceDefault = "default"
+)
+
+// ErrBindTarget Bind 的参数不是结构体指针
+var ErrBindTarget = errors.New("bind: target must be a non-nil pointer to struct")
+
+// ErrBindContentType 请求体的编码类型没有对应的编解码器
+var ErrBindContentType = errors.New("bind: unsupported content type")
+
+// FieldError 单个字段绑定失败的原因
+type FieldError struct {
+	Field  string // 结构体字段名，嵌入结构体的字段以 . 连接 eg: Page.Size
+	Source string // 值的来源 path、query、default，请求体为解码所用编解码器的名称 eg: json、msgpack
+	Name   string // 标签中的名称，请求体为以 . 连接的键名
+	Value  string // 原始值
+	Err    error  // 转换失败的原因
+}
+
+func (e *FieldError) Error() string {
+	// 请求体无法解码，无法确定字段
+	if e.Field == "" {
+		return fmt.Sprintf("bind: invalid %s body: %v", e.Source, e.Err)
+	}
+	return fmt.Sprintf("bind: field '%s': invalid %s value '%s' for '%s': %v", e.Field, e.Source, e.Value, e.Name, e.Err)
+}
+
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+// BindError 汇总所有绑定失败的字段
+type BindError struct {
+	Errors []*FieldError
+}
+
+func (e *BindError) Error() string {
+	msgs := make([]string, len(e.Errors))
+	for i, fe := range e.Errors {
+		msgs[i] = fe.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Unwrap 支持 errors.Is 和 errors.As 判断任意一个字段的错误
+func (e *BindError) Unwrap() []error {
+	errs := make([]error, len(e.Errors))
+	for i, fe := range e.Errors {
+		errs[i] = fe
+	}
+	return errs
+}
+
+// Bind 根据结构体标签将路由参数、查询参数和 JSON 请求体绑定到 obj，obj 必须是结构体指针
+//
+//	path:"name"     路由参数
+//	query:"q"       查询参数，切片字段接收所有同名的值
+//	json:"..."      请求体，根据 Request.ContentType 从 Codecs 中选择编解码器解析，为空时使用 JSON
+//	default:"..."   没有任何来源提供值时使用的默认值，切片字段以 , 分隔
+//
+// 绑定顺序为 default > 请求体 > query > path，后者覆盖前者
+// 字段支持基本类型、time.Duration、实现了 encoding.TextUnmarshaler 的类型以及它们的指针和切片
+// 所有字段的转换错误汇总为 *BindError 返回
+func (ctx *RequestContext) Bind(obj interface{}) error {
+	rv := reflect.ValueOf(obj)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return ErrBindTarget
+	}
+	rv = rv.Elem()
+	fields := cachedBindFields(rv.Type())
+	var errs []*FieldError
+
+	// 默认值
+	for _, f := range fields {
+		if f.def != nil {
+			errs = f.set(rv, bindSourceDefault, f.def, errs)
+		}
+	}
+
+	// 请求体
+	if err := ctx.BindBody(obj); err != nil {
+		var bindErr *BindError
+		if !errors.As(err, &bindErr) {
+			return err
+		}
+		errs = append(errs, bindErr.Errors...)
+	}
+
+	// 查询参数
+	query := ctx.QueryArgs()
+	for _, f := range fields {
+		if f.query != "" && query.Has(f.query) {
+			errs = f.set(rv, bindSourceQuery, ctx.QueryArray(f.query), errs)
+		}
+	}
+
+	// 路由参数
+	for _, f := range fields {
+		if value, ok := ctx.Params.Get(f.path); f.path != "" && ok {
+			errs = f.set(rv, bindSourcePath, []string{value}, errs)
+		}
+	}
+
+	if len(errs) > 0 {
+		return &BindError{Errors: errs}
+	}
+	return nil
+}
+
+// BindBody 根据 Request.ContentType 从 Codecs 中选择编解码器，将请求体解码到 obj
+// 请求体为空时不做任何处理，编码类型为空时使用 JSON
+// 解码失败时返回包含一个 FieldError 的 *BindError，JSON 类型错误会指出对应的字段
+func (ctx *RequestContext) BindBody(obj interface{}) error {
+	if len(ctx.Request.Body) == 0 {
+		return nil
+	}
+	c := ctx.Codecs().Default()
+	if ct := ctx.Request.ContentType; ct != "" {
+		var ok bool
+		if c, ok = ctx.Codecs().Lookup(ct); !ok {
+			return fmt.Errorf("%w: '%s'", ErrBindContentType, ct)
+		}
+	}
+	if err := c.Unmarshal(ctx.Request.Body, obj); err != nil {
+		return &BindError{Errors: []*FieldError{bodyFieldError(c.Name(), reflect.TypeOf(obj), err)}}
+	}
+	return nil
+}
+
+// bodyFieldError 将请求体的解码错误转换为 FieldError，source 为解码所用编解码器的名称
+// 类型错误时 Field 为 t 中的结构体字段名，与 path、query 的错误一致，Name 为请求体中的键名
+func bodyFieldError(source string, t reflect.Type, err error) *FieldError {
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		return &FieldError{Field: bodyFieldPath(t, typeErr.Field), Source: source, Name: typeErr.Field, Value: typeErr.Value, Err: err}
+	}
+	return &FieldError{Source: source, Err: err}
+}
+
+// bodyFieldPath 将以 . 连接的请求体键名转换为结构体字段名 eg: address.city -> Address.City
+// 嵌入结构体的字段包含嵌入结构体的名称，无法对应到结构体字段时返回原键名
+func bodyFieldPath(t reflect.Type, key string) string {
+	var path []string
+	for _, name := range strings.Split(key, ".") {
+		for t.Kind() == reflect.Pointer {
+			t = t.Elem()
+		}
+		if t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+			// 切片的下标保留原样 eg: items.0.price -> Items.0.Price
+			if _, err := strconv.Atoi(name); err == nil {
+				path = append(path, name)
+				t = t.Elem()
+				continue
+			}
+			t = elemType(t)
+		}
+		switch t.Kind() {
+		case reflect.Map:
+			// map 的键没有对应的字段，保留键名
+			path = append(path, name)
+			t = t.Elem()
+		case reflect.Struct:
+			sf, names, ok := bodyField(t, name)
+			if !ok {
+				return key
+			}
+			path = append(path, names...)
+			t = sf.Type
+		default:
+			return key
+		}
+	}
+	return strings.Join(path, ".")
+}
+
+// bodyField 在 t 中查找 JSON 名称为 name 的字段，返回字段以及从 t 开始的字段名
+// name 也可以是嵌入结构体的名称，没有直接匹配的字段时在嵌入结构体中查找
+func bodyField(t reflect.Type, name string) (reflect.StructField, []string, bool) {
+	var embedded []reflect.StructField
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		tag := sf.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		jsonName, _, _ := strings.Cut(tag, ",")
+		if sf.Anonymous && jsonName == "" && elemType(sf.Type).Kind() == reflect.Struct {
+			if sf.Name == name {
+				return sf, []string{sf.Name}, true
+			}
+			embedded = append(embedded, sf)
+			continue
+		}
+		if !sf.IsExported() {
+			continue
+		}
+		if jsonName == "" {
+			jsonName = sf.Name
+		}
+		if jsonName == name {
+			return sf, []string{sf.Name}, true
+		}
+	}
+	for _, e := range embedded {
+		if sf, names, ok := bodyField(elemType(e.Type), name); ok {
+			return sf, append([]string{e.Name}, names...), true
+		}
+	}
+	return reflect.StructField{}, nil, false
+}
+
+// elemType 返回指针、切片和数组最终指向的元素类型
+func elemType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Pointer || t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+		t = t.Elem()
+	}
+	return t
+}
+
+// bindField 需要绑定的结构体字段
+type bindField struct {
+	index []int    // reflect.Value.FieldByIndex 使用的下标
+	name  string   // 字段名
+	path  string   // path 标签
+	query string   // query 标签
+	def   []string // default 标签，没有时为 nil
+}
+
+// bindFieldsCache 缓存每种结构体类型解析出的字段 reflect.Type -> []bindField
+var bindFieldsCache sync.Map
+
+func cachedBindFields(t reflect.Type) []bindField {
+	if fields, ok := bindFieldsCache.Load(t); ok {
+		return fields.([]bindField)
+	}
+	fields, _ := bindFieldsCache.LoadOrStore(t, parseBindFields(t, nil, ""))
+	return fields.([]bindField)
+}
+
+// parseBindFields 解析结构体中带有 path、query、default 标签的字段，递归处理非指针的嵌入结构体
+func parseBindFields(t reflect.Type, index []int, prefix string) (fields []bindField) {
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		idx := append(append([]int(nil), index...), i)
+		if sf.Anonymous && sf.Type.Kind() == reflect.Struct {
+			fields = append(fields, parseBindFields(sf.Type, idx, prefix+sf.Name+".")...)
+			continue
+		}
+		if !sf.IsExported() {
+			continue
+		}
+		f := bindField{index: idx, name: prefix + sf.Name, path: tagName(sf, bindSourcePath), query: tagName(sf, bindSourceQuery)}
+		if def, ok := sf.Tag.Lookup(bindSourceDefault); ok {
+			f.def = []string{def}
+			if sf.Type.Kind() == reflect.Slice {
+				f.def = strings.Split(def, ",")
+			}
+		}
+		if f.path != "" || f.query != "" || f.def != nil {
+			fields = append(fields, f)
+		}
+	}
+	return
+}
+
+// tagName 返回标签中 , 之前的名称，- 表示忽略
+func tagName(sf reflect.StructField, key string) string {
+	name, _, _ := strings.Cut(sf.Tag.Get(key), ",")
+	if name == "-" {
+		return ""
+	}
+	return name
+}
+
+// set 将 values 转换后写入字段，失败时追加到 errs
+func (f *bindField) set(rv reflect.Value, source string, values []string, errs []*FieldError) []*FieldError {
+	name := f.name
+	switch source {
+	case bindSourcePath:
+		name = f.path
+	case bindSourceQuery:
+		name = f.query
+	}
+	if err := setValue(rv.FieldByIndex(f.index), values); err != nil {
+		return append(errs, &FieldError{Field: f.name, Source: source, Name: name, Value: strings.Join(values, ","), Err: err})
+	}
+	return errs
+}
+
+var (
+	durationType        = reflect.TypeOf(time.Duration(0))
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// setValue 将 values 转换为 v 的类型后写入 v
+// 切片使用全部的值，其他类型使用第一个值
+func setValue(v reflect.Value, values []string) error {
+	if len(values) == 0 {
+		return nil
+	}
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return setValue(v.Elem(), values)
+	}
+	if reflect.PointerTo(v.Type()).Implements(textUnmarshalerType) {
+		return v.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(values[0]))
+	}
+	if v.Kind() == reflect.Slice {
+		slice := reflect.MakeSlice(v.Type(), len(values), len(values))
+		for i, value := range values {
+			if err := setValue(slice.Index(i), []string{value}); err != nil {
+				return err
+			}
+		}
+		v.Set(slice)
+		return nil
+	}
+	return setScalar(v, values[0])
+}
+
+// setScalar 将 value 转换为基本类型后写入 v
+func setScalar(v reflect.Value, value string) (err error) {
+	if v.Type() == durationType {
+		d, err := time.ParseDuration(value)
+		if err == nil {
+			v.SetInt(int64(d))
+		}
+		return err
+	}
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(value)
+	case reflect.Bool:
+		var b bool
+		if b, err = strconv.ParseBool(value); err == nil {
+			v.SetBool(b)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		var i int64
+		if i, err = strconv.ParseInt(value, 10, v.Type().Bits()); err == nil {
+			v.SetInt(i)
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		var u uint64
+		if u, err = strconv.ParseUint(value, 10, v.Type().Bits()); err == nil {
+			v.SetUint(u)
+		}
+	case reflect.Float32, reflect.Float64:
+		var f float64
+		if f, err = strconv.ParseFloat(value, v.Type().Bits()); err == nil {
+			v.SetFloat(f)
+		}
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+	// strconv 的错误已经包含原始值，只保留原因
+	var numErr *strconv.NumError
+	if errors.As(err, &numErr) {
+		err = numErr.Err
+	}
+	return err
+}
@@ -0,0 +1,149 @@
+package server
+
+import (
+	"errors"
+	"github.com/Yuki-J1/wailsrouter/pkg/codec"
+	"github.com/Yuki-J1/wailsrouter/pkg/codec/msgpack"
+	"github.com/cloudwego/hertz/pkg/common/test/assert"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+type bindPage struct {
+	Page int `query:"page" default:"1"`
+	Size int `query:"size" default:"20"`
+}
+
+type bindRequest struct {
+	bindPage
+	ID      int64         `path:"id"`
+	Tags    []string      `query:"tag" default:"a,b"`
+	Verbose *bool         `query:"verbose"`
+	Timeout time.Duration `query:"timeout" default:"1s"`
+	Since   time.Time     `query:"since"`
+	Name    string        `json:"name" default:"anonymous"`
+	Age     uint8         `json:"age"`
+	Ignored string        `query:"-"`
+}
+
+func TestRequestContext_Bind(t *testing.T) {
+	ctx := NewContext(0)
+	ctx.Params = Params{{Key: "id", Value: "42"}}
+	ctx.Path = []byte("/user/42")
+	ctx.SetQueryString([]byte("page=3&tag=x&tag=y&verbose=true&since=2023-11-26T00%3A00%3A00Z&Ignored=1"))
+	ctx.Request.ContentType = MIMEApplicationJSON
+	ctx.Request.Body = []byte(`{"age":18}`)
+
+	var req bindRequest
+	assert.Nil(t, ctx.Bind(&req))
+	assert.DeepEqual(t, int64(42), req.ID)
+	assert.DeepEqual(t, 3, req.Page)
+	assert.DeepEqual(t, 20, req.Size)
+	assert.DeepEqual(t, []string{"x", "y"}, req.Tags)
+	assert.True(t, req.Verbose != nil && *req.Verbose)
+	assert.DeepEqual(t, time.Second, req.Timeout)
+	assert.DeepEqual(t, time.Date(2023, 11, 26, 0, 0, 0, 0, time.UTC), req.Since)
+	assert.DeepEqual(t, "anonymous", req.Name)
+	assert.DeepEqual(t, uint8(18), req.Age)
+	assert.DeepEqual(t, "", req.Ignored)
+
+	// 没有请求体和查询参数时使用默认值
+	ctx.SetQueryString(nil)
+	ctx.Request.Body = nil
+	req = bindRequest{}
+	assert.Nil(t, ctx.Bind(&req))
+	assert.DeepEqual(t, 1, req.Page)
+	assert.DeepEqual(t, []string{"a", "b"}, req.Tags)
+}
+
+func TestRequestContext_BindError(t *testing.T) {
+	ctx := NewContext(0)
+	ctx.Params = Params{{Key: "id", Value: "abc"}}
+	ctx.Path = []byte("/user/abc")
+	ctx.SetQueryString([]byte("page=x&timeout=soon"))
+	ctx.Request.Body = []byte(`{"age":300}`)
+
+	var req bindRequest
+	err := ctx.Bind(&req)
+	var bindErr *BindError
+	assert.True(t, errors.As(err, &bindErr))
+	assert.DeepEqual(t, 4, len(bindErr.Errors))
+	fields := make(map[string]string)
+	for _, fe := range bindErr.Errors {
+		fields[fe.Field] = fe.Source + ":" + fe.Name
+	}
+	assert.DeepEqual(t, map[string]string{"Age": "json:age", "bindPage.Page": "query:page", "Timeout": "query:timeout", "ID": "path:id"}, fields)
+	assert.True(t, errors.Is(err, strconv.ErrSyntax))
+	// 其他字段仍然被绑定
+	assert.DeepEqual(t, 20, req.Size)
+
+	assert.True(t, errors.Is(ctx.Bind(req), ErrBindTarget))
+	ctx.Request.ContentType = MIMETextPlain
+	assert.True(t, errors.Is(ctx.Bind(&req), ErrBindContentType))
+	ctx.Request.ContentType = MIMEApplicationJSON
+	ctx.Params = nil
+	ctx.SetQueryString(nil)
+	ctx.Request.Body = []byte(`{`)
+	err = ctx.Bind(&req)
+	assert.True(t, errors.As(err, &bindErr))
+	assert.DeepEqual(t, 1, len(bindErr.Errors))
+	assert.DeepEqual(t, "", bindErr.Errors[0].Field)
+	assert.DeepEqual(t, "bind: invalid json body: unexpected end of JSON input", bindErr.Errors[0].Error())
+
+	// 其他编解码器的解码错误同样汇总为 *BindError
+	ctx.SetCodecs(codec.NewRegistry(msgpack.Codec{}))
+	ctx.Request.ContentType = "application/msgpack"
+	ctx.Request.Body, _ = msgpack.Codec{}.Marshal(map[string]string{"age": "old"})
+	err = ctx.Bind(&req)
+	assert.True(t, errors.As(err, &bindErr))
+	assert.DeepEqual(t, 1, len(bindErr.Errors))
+	assert.DeepEqual(t, "msgpack", bindErr.Errors[0].Source)
+	assert.True(t, strings.HasPrefix(bindErr.Errors[0].Error(), "bind: invalid msgpack body: "))
+}
+
+type bindOrder struct {
+	bindPage
+	Address struct {
+		City int `json:"city"`
+	} `json:"address"`
+	Items []struct {
+		Price float64 `json:"price"`
+	} `json:"items"`
+	Meta map[string]struct {
+		Count int `json:"count"`
+	} `json:"meta"`
+}
+
+func TestRequestContext_BindBodyFieldError(t *testing.T) {
+	ctx := NewContext(0)
+	var bindErr *BindError
+	ctx.Request.Body = []byte(`{"address":{"city":"x"}}`)
+	assert.True(t, errors.As(ctx.BindBody(&bindOrder{}), &bindErr))
+	assert.DeepEqual(t, "Address.City", bindErr.Errors[0].Field)
+	assert.DeepEqual(t, "address.city", bindErr.Errors[0].Name)
+	assert.DeepEqual(t, "json", bindErr.Errors[0].Source)
+	ctx.Request.Body = []byte(`{"Page":"x"}`)
+	assert.True(t, errors.As(ctx.BindBody(&bindOrder{}), &bindErr))
+	assert.DeepEqual(t, "bindPage.Page", bindErr.Errors[0].Field)
+
+	typ := reflect.TypeOf(&bindOrder{})
+	tests := []struct {
+		key   string
+		field string
+	}{
+		// 嵌入结构体的名称可能不在键名中
+		{"Page", "bindPage.Page"},
+		{"bindPage.Page", "bindPage.Page"},
+		{"items.price", "Items.Price"},
+		{"items.0.price", "Items.0.Price"},
+		{"meta.a.count", "Meta.a.Count"},
+		{"unknown", "unknown"},
+		{"address.unknown", "address.unknown"},
+	}
+	for _, tt := range tests {
+		assert.DeepEqual(t, tt.field, bodyFieldPath(typ, tt.key))
+	}
+}
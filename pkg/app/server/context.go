@@ -2,6 +2,7 @@ package server
 
 import (
 	"context"
+	"github.com/Yuki-J1/wailsrouter/pkg/codec"
 	"sync"
 )
 
@@ -10,6 +11,19 @@ type HandlersChain []HandlerFunc
 
 type HandlerFunc func(c context.Context, ctx *RequestContext)
 
+// Last 返回调用链中的最后一个 handler，通常是业务 handler
+// 调用链为空时返回 nil
+func (c HandlersChain) Last() HandlerFunc {
+	if length := len(c); length > 0 {
+		return c[length-1]
+	}
+	return nil
+}
+
+// abortIndex 中止调用链时 index 被设置的值
+// RouterGroup.combineHandlers 限制 handlers 最多 63 个，因此 index 不会自然到达该值
+const abortIndex int8 = 63
+
 type RequestContext struct {
 	Params   Params
 	handlers HandlersChain // 查询到的处理函数
@@ -18,22 +32,105 @@ type RequestContext struct {
 	Keys     map[string]interface{}
 	index    int8 // 调用链指针
 	Path     []byte
+	Request  Request  // 请求数据
+	Response Response // 响应数据
+	Errors   Errors   // handler 通过 ctx.Error 记录的所有错误，按记录顺序排列
+
+	queryArgs   Args            // 解析后的查询参数
+	queryParsed bool            // Request.QueryString 是否已经解析到 queryArgs
+	queryBuf    []byte          // SetQueryString 复制查询字符串的缓冲区，重置时保留
+	codecs      *codec.Registry // 编解码器，重置时保留
 }
 
 func NewContext(maxParams uint16) *RequestContext {
 	v := make(Params, 0, maxParams)
 	ctx := &RequestContext{Params: v, index: -1}
+	ctx.Response.reset()
 	return ctx
 }
 
+// Reset 将请求上下文恢复为初始状态，以便从对象池中复用
+func (ctx *RequestContext) Reset() {
+	ctx.Params = ctx.Params[:0]
+	ctx.handlers = nil
+	ctx.fullPath = ""
+	ctx.index = -1
+	ctx.Path = ctx.Path[:0]
+	ctx.Request = Request{}
+	ctx.Response.reset()
+	ctx.Errors = nil
+	ctx.queryArgs.Reset()
+	ctx.queryParsed = false
+
+	ctx.mu.Lock()
+	ctx.Keys = nil
+	ctx.mu.Unlock()
+}
+
+// Copy 返回一个可以在 handler 返回后继续安全使用的副本
+// 当需要将请求上下文交给其他 goroutine 时，必须使用副本而不是原请求上下文
+// 副本不携带 handlers，在副本上调用 Next 不会执行任何 handler
+func (ctx *RequestContext) Copy() *RequestContext {
+	cp := &RequestContext{
+		Params:   make(Params, len(ctx.Params)),
+		fullPath: ctx.fullPath,
+		index:    abortIndex,
+		Path:     append([]byte(nil), ctx.Path...),
+		Request:  ctx.Request,
+		Response: ctx.Response,
+		codecs:   ctx.codecs,
+	}
+	copy(cp.Params, ctx.Params)
+	cp.Errors = append(Errors(nil), ctx.Errors...)
+	cp.Request.Body = append([]byte(nil), ctx.Request.Body...)
+	cp.Request.QueryString = append([]byte(nil), ctx.Request.QueryString...)
+	cp.Response.Body = append([]byte(nil), ctx.Response.Body...)
+	if ctx.Request.Meta != nil {
+		cp.Request.Meta = make(map[string]string, len(ctx.Request.Meta))
+		for k, v := range ctx.Request.Meta {
+			cp.Request.Meta[k] = v
+		}
+	}
+
+	ctx.mu.RLock()
+	if ctx.Keys != nil {
+		cp.Keys = make(map[string]interface{}, len(ctx.Keys))
+		for k, v := range ctx.Keys {
+			cp.Keys[k] = v
+		}
+	}
+	ctx.mu.RUnlock()
+	return cp
+}
+
 func (ctx *RequestContext) SetHandlers(hc HandlersChain) {
 	ctx.handlers = hc
 }
 
+// Handlers 返回当前的调用链
+func (ctx *RequestContext) Handlers() HandlersChain {
+	return ctx.handlers
+}
+
+// GetIndex 返回调用链指针
+func (ctx *RequestContext) GetIndex() int8 {
+	return ctx.index
+}
+
+// SetIndex 设置调用链指针
+func (ctx *RequestContext) SetIndex(index int8) {
+	ctx.index = index
+}
+
 func (ctx *RequestContext) SetFullPath(p string) {
 	ctx.fullPath = p
 }
 
+// FullPath 返回匹配到的路由规则，未匹配时为空字符串
+func (ctx *RequestContext) FullPath() string {
+	return ctx.fullPath
+}
+
 func (ctx *RequestContext) Next(c context.Context) {
 	// ctx.index 指向当前执行的handler
 	// index++ 表示指针指向下一个handler
@@ -46,3 +143,32 @@ func (ctx *RequestContext) Next(c context.Context) {
 		ctx.index++
 	}
 }
+
+// Abort 阻止调用链中后续的 handler 执行，当前 handler 会继续执行完毕
+// 常用于鉴权、参数校验等中间件提前结束请求
+func (ctx *RequestContext) Abort() {
+	ctx.index = abortIndex
+}
+
+// IsAborted 调用链是否已被中止
+func (ctx *RequestContext) IsAborted() bool {
+	return ctx.index >= abortIndex
+}
+
+// AbortWithStatus 设置状态码并中止调用链
+func (ctx *RequestContext) AbortWithStatus(code int) {
+	ctx.Status(code)
+	ctx.Abort()
+}
+
+// AbortWithStatusJSON 写入 JSON 响应并中止调用链
+func (ctx *RequestContext) AbortWithStatusJSON(code int, obj interface{}) {
+	ctx.Abort()
+	ctx.JSON(code, obj)
+}
+
+// AbortWithError 设置状态码、记录错误并中止调用链，返回 err 本身
+func (ctx *RequestContext) AbortWithError(code int, err error) error {
+	ctx.AbortWithStatus(code)
+	return ctx.Error(err)
+}
@@ -1,5 +1,15 @@
 package server
 
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
 // Param is a single URL parameter, consisting of a key and a value.
 type Param struct {
 	Key   string
@@ -28,3 +38,169 @@ func (ps Params) ByName(name string) (va string) {
 	va, _ = ps.Get(name)
 	return
 }
+
+// ErrParamNotFound is returned by the typed accessors when no Param matches the given name.
+var ErrParamNotFound = errors.New("param not found")
+
+// ParamError describes a Param which is missing or can not be converted to the requested type.
+type ParamError struct {
+	Name  string // name of the param
+	Value string // raw value of the param
+	Type  string // requested type, e.g. "int"
+	Route string // matched route pattern, set by the RequestContext accessors
+	Err   error  // ErrParamNotFound or the underlying parse error
+}
+
+func (e *ParamError) Error() string {
+	msg := "param '" + e.Name + "'"
+	if e.Route != "" {
+		msg += " of route '" + e.Route + "'"
+	}
+	if e.Err == ErrParamNotFound {
+		return msg + " not found"
+	}
+	return fmt.Sprintf("%s: invalid %s value '%s': %v", msg, e.Type, e.Value, e.Err)
+}
+
+func (e *ParamError) Unwrap() error {
+	return e.Err
+}
+
+// parseParam looks up the Param by name and converts its value with parse.
+// The returned error is always a *ParamError.
+func parseParam[T any](ps Params, name, typ string, parse func(string) (T, error)) (T, error) {
+	value, ok := ps.Get(name)
+	if !ok {
+		var zero T
+		return zero, &ParamError{Name: name, Type: typ, Err: ErrParamNotFound}
+	}
+	v, err := parse(value)
+	if err != nil {
+		// strconv errors already contain the value, keep only the reason
+		var numErr *strconv.NumError
+		if errors.As(err, &numErr) {
+			err = numErr.Err
+		}
+		return v, &ParamError{Name: name, Value: value, Type: typ, Err: err}
+	}
+	return v, nil
+}
+
+// Int returns the value of the named Param converted to int.
+func (ps Params) Int(name string) (int, error) {
+	return parseParam(ps, name, "int", strconv.Atoi)
+}
+
+// Int64 returns the value of the named Param converted to int64.
+func (ps Params) Int64(name string) (int64, error) {
+	return parseParam(ps, name, "int64", func(s string) (int64, error) {
+		return strconv.ParseInt(s, 10, 64)
+	})
+}
+
+// Uint returns the value of the named Param converted to uint.
+func (ps Params) Uint(name string) (uint, error) {
+	return parseParam(ps, name, "uint", func(s string) (uint, error) {
+		v, err := strconv.ParseUint(s, 10, 0)
+		return uint(v), err
+	})
+}
+
+// Bool returns the value of the named Param converted to bool.
+// It accepts the values accepted by strconv.ParseBool.
+func (ps Params) Bool(name string) (bool, error) {
+	return parseParam(ps, name, "bool", strconv.ParseBool)
+}
+
+// Float returns the value of the named Param converted to float64.
+func (ps Params) Float(name string) (float64, error) {
+	return parseParam(ps, name, "float", func(s string) (float64, error) {
+		return strconv.ParseFloat(s, 64)
+	})
+}
+
+// Duration returns the value of the named Param parsed by time.ParseDuration, e.g. "1h30m".
+func (ps Params) Duration(name string) (time.Duration, error) {
+	return parseParam(ps, name, "duration", time.ParseDuration)
+}
+
+// UUID returns the value of the named Param as a lower-case UUID in the
+// 8-4-4-4-12 form, e.g. "123e4567-e89b-12d3-a456-426614174000".
+func (ps Params) UUID(name string) (string, error) {
+	return parseParam(ps, name, "uuid", parseUUID)
+}
+
+// Time returns the value of the named Param parsed by time.Parse with the given layout.
+func (ps Params) Time(name, layout string) (time.Time, error) {
+	return parseParam(ps, name, "time", func(s string) (time.Time, error) {
+		return time.Parse(layout, s)
+	})
+}
+
+var errInvalidUUID = errors.New("invalid UUID format")
+
+func parseUUID(s string) (string, error) {
+	if len(s) != 36 || s[8] != '-' || s[13] != '-' || s[18] != '-' || s[23] != '-' {
+		return "", errInvalidUUID
+	}
+	var b [16]byte
+	if _, err := hex.Decode(b[:], []byte(s[0:8]+s[9:13]+s[14:18]+s[19:23]+s[24:])); err != nil {
+		return "", errInvalidUUID
+	}
+	return strings.ToLower(s), nil
+}
+
+// mustParam converts the named Param with get. On failure the route pattern is
+// added to the error, and the request is aborted with 400 Bad Request.
+func mustParam[T any](ctx *RequestContext, get func(Params) (T, error)) (T, bool) {
+	v, err := get(ctx.Params)
+	if err != nil {
+		if pe, ok := err.(*ParamError); ok {
+			pe.Route = ctx.fullPath
+		}
+		ctx.AbortWithError(http.StatusBadRequest, err)
+		return v, false
+	}
+	return v, true
+}
+
+// MustParamInt is like Params.Int, but aborts the request with 400 Bad Request
+// and records the *ParamError on failure. The handler should return when ok is false.
+func (ctx *RequestContext) MustParamInt(name string) (v int, ok bool) {
+	return mustParam(ctx, func(ps Params) (int, error) { return ps.Int(name) })
+}
+
+// MustParamInt64 is like Params.Int64, but aborts the request on failure, see MustParamInt.
+func (ctx *RequestContext) MustParamInt64(name string) (v int64, ok bool) {
+	return mustParam(ctx, func(ps Params) (int64, error) { return ps.Int64(name) })
+}
+
+// MustParamUint is like Params.Uint, but aborts the request on failure, see MustParamInt.
+func (ctx *RequestContext) MustParamUint(name string) (v uint, ok bool) {
+	return mustParam(ctx, func(ps Params) (uint, error) { return ps.Uint(name) })
+}
+
+// MustParamBool is like Params.Bool, but aborts the request on failure, see MustParamInt.
+func (ctx *RequestContext) MustParamBool(name string) (v, ok bool) {
+	return mustParam(ctx, func(ps Params) (bool, error) { return ps.Bool(name) })
+}
+
+// MustParamFloat is like Params.Float, but aborts the request on failure, see MustParamInt.
+func (ctx *RequestContext) MustParamFloat(name string) (v float64, ok bool) {
+	return mustParam(ctx, func(ps Params) (float64, error) { return ps.Float(name) })
+}
+
+// MustParamDuration is like Params.Duration, but aborts the request on failure, see MustParamInt.
+func (ctx *RequestContext) MustParamDuration(name string) (v time.Duration, ok bool) {
+	return mustParam(ctx, func(ps Params) (time.Duration, error) { return ps.Duration(name) })
+}
+
+// MustParamUUID is like Params.UUID, but aborts the request on failure, see MustParamInt.
+func (ctx *RequestContext) MustParamUUID(name string) (v string, ok bool) {
+	return mustParam(ctx, func(ps Params) (string, error) { return ps.UUID(name) })
+}
+
+// MustParamTime is like Params.Time, but aborts the request on failure, see MustParamInt.
+func (ctx *RequestContext) MustParamTime(name, layout string) (v time.Time, ok bool) {
+	return mustParam(ctx, func(ps Params) (time.Time, error) { return ps.Time(name, layout) })
+}
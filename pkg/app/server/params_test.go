@@ -0,0 +1,91 @@
+package server
+
+import (
+	"errors"
+	"github.com/cloudwego/hertz/pkg/common/test/assert"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestParams_Typed(t *testing.T) {
+	ps := Params{
+		{Key: "id", Value: "42"},
+		{Key: "neg", Value: "-7"},
+		{Key: "ok", Value: "true"},
+		{Key: "price", Value: "9.5"},
+		{Key: "ttl", Value: "1m30s"},
+		{Key: "uuid", Value: "123E4567-E89B-12D3-A456-426614174000"},
+		{Key: "day", Value: "2023-11-26"},
+		{Key: "name", Value: "YKJ"},
+	}
+
+	i, err := ps.Int("id")
+	assert.Nil(t, err)
+	assert.DeepEqual(t, 42, i)
+	i64, err := ps.Int64("neg")
+	assert.Nil(t, err)
+	assert.DeepEqual(t, int64(-7), i64)
+	u, err := ps.Uint("id")
+	assert.Nil(t, err)
+	assert.DeepEqual(t, uint(42), u)
+	b, err := ps.Bool("ok")
+	assert.Nil(t, err)
+	assert.True(t, b)
+	f, err := ps.Float("price")
+	assert.Nil(t, err)
+	assert.DeepEqual(t, 9.5, f)
+	d, err := ps.Duration("ttl")
+	assert.Nil(t, err)
+	assert.DeepEqual(t, 90*time.Second, d)
+	id, err := ps.UUID("uuid")
+	assert.Nil(t, err)
+	assert.DeepEqual(t, "123e4567-e89b-12d3-a456-426614174000", id)
+	day, err := ps.Time("day", time.DateOnly)
+	assert.Nil(t, err)
+	assert.DeepEqual(t, time.Date(2023, 11, 26, 0, 0, 0, 0, time.UTC), day)
+
+	tests := []struct {
+		get  func() error
+		name string
+		typ  string
+		err  error
+		msg  string
+	}{
+		{func() error { _, err := ps.Int("name"); return err }, "name", "int", strconv.ErrSyntax, "param 'name': invalid int value 'YKJ': invalid syntax"},
+		{func() error { _, err := ps.Uint("neg"); return err }, "neg", "uint", strconv.ErrSyntax, "param 'neg': invalid uint value '-7': invalid syntax"},
+		{func() error { _, err := ps.Int("missing"); return err }, "missing", "int", ErrParamNotFound, "param 'missing' not found"},
+		{func() error { _, err := ps.UUID("id"); return err }, "id", "uuid", errInvalidUUID, "param 'id': invalid uuid value '42': invalid UUID format"},
+		{func() error { _, err := ps.Bool("price"); return err }, "price", "bool", strconv.ErrSyntax, "param 'price': invalid bool value '9.5': invalid syntax"},
+	}
+	for _, tt := range tests {
+		err := tt.get()
+		var pe *ParamError
+		assert.True(t, errors.As(err, &pe))
+		assert.DeepEqual(t, tt.name, pe.Name)
+		assert.DeepEqual(t, tt.typ, pe.Type)
+		assert.True(t, errors.Is(err, tt.err))
+		assert.DeepEqual(t, tt.msg, err.Error())
+	}
+}
+
+func TestRequestContext_MustParam(t *testing.T) {
+	ctx := NewContext(0)
+	ctx.Params = Params{{Key: "id", Value: "42"}}
+	ctx.SetFullPath("/user/:id")
+
+	id, ok := ctx.MustParamInt("id")
+	assert.True(t, ok)
+	assert.DeepEqual(t, 42, id)
+	assert.False(t, ctx.IsAborted())
+
+	_, ok = ctx.MustParamUUID("id")
+	assert.False(t, ok)
+	assert.True(t, ctx.IsAborted())
+	assert.DeepEqual(t, http.StatusBadRequest, ctx.Response.StatusCode)
+	var pe *ParamError
+	assert.True(t, errors.As(ctx.Response.Err, &pe))
+	assert.DeepEqual(t, "/user/:id", pe.Route)
+	assert.DeepEqual(t, "param 'id' of route '/user/:id': invalid uuid value '42': invalid UUID format", pe.Error())
+}
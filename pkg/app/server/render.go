@@ -0,0 +1,112 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/Yuki-J1/wailsrouter/pkg/codec"
+	"net/http"
+)
+
+// Status 设置响应状态码
+func (ctx *RequestContext) Status(code int) {
+	ctx.Response.StatusCode = code
+}
+
+// Data 将 data 原样写入响应体，并设置状态码和编码类型
+func (ctx *RequestContext) Data(code int, contentType string, data []byte) {
+	ctx.Response.StatusCode = code
+	ctx.Response.ContentType = contentType
+	ctx.Response.Body = data
+}
+
+// String 将格式化后的字符串写入响应体
+func (ctx *RequestContext) String(code int, format string, values ...interface{}) {
+	if len(values) > 0 {
+		format = fmt.Sprintf(format, values...)
+	}
+	ctx.Data(code, MIMETextPlain, []byte(format))
+}
+
+// JSON 将 obj 序列化为 JSON 写入响应体
+// 序列化失败时状态码置为 500，并记录错误
+func (ctx *RequestContext) JSON(code int, obj interface{}) {
+	data, err := json.Marshal(obj)
+	if err != nil {
+		ctx.Status(http.StatusInternalServerError)
+		ctx.Error(err)
+		return
+	}
+	ctx.Data(code, MIMEApplicationJSON, data)
+}
+
+// defaultCodecs 没有设置编解码器时使用，只有 JSON
+var defaultCodecs = codec.NewRegistry()
+
+// SetCodecs 设置编解码器，Engine 创建请求上下文时设置为 Engine.Codecs
+func (ctx *RequestContext) SetCodecs(codecs *codec.Registry) {
+	ctx.codecs = codecs
+}
+
+// Codecs 返回编解码器，没有设置时只有 JSON
+func (ctx *RequestContext) Codecs() *codec.Registry {
+	if ctx.codecs == nil {
+		return defaultCodecs
+	}
+	return ctx.codecs
+}
+
+// ResponseCodec 返回编码响应体使用的编解码器
+// 依次根据 Request.Meta 中的 Accept、Request.ContentType 选择，都没有匹配时使用 JSON
+func (ctx *RequestContext) ResponseCodec() codec.Codec {
+	codecs := ctx.Codecs()
+	if c, ok := codecs.Negotiate(ctx.Request.GetMeta(MetaAccept)); ok {
+		return c
+	}
+	if c, ok := codecs.Lookup(ctx.Request.ContentType); ok {
+		return c
+	}
+	return codecs.Default()
+}
+
+// Render 使用 ResponseCodec 将 obj 编码后写入响应体
+// 编码失败时状态码置为 500，并记录错误
+func (ctx *RequestContext) Render(code int, obj interface{}) {
+	c := ctx.ResponseCodec()
+	data, err := c.Marshal(obj)
+	if err != nil {
+		ctx.Status(http.StatusInternalServerError)
+		ctx.Error(err)
+		return
+	}
+	ctx.Data(code, c.ContentType(), data)
+}
+
+// Error 将 handler 产生的错误追加到 ctx.Errors，并设置为 Response.Err，返回 err 本身
+func (ctx *RequestContext) Error(err error) error {
+	ctx.Errors = append(ctx.Errors, err)
+	ctx.Response.Err = err
+	return err
+}
+
+// Errors 请求处理过程中记录的错误
+type Errors []error
+
+// Last 返回最后一个错误，没有错误时返回 nil
+func (errs Errors) Last() error {
+	if length := len(errs); length > 0 {
+		return errs[length-1]
+	}
+	return nil
+}
+
+// Strings 返回所有错误的错误信息
+func (errs Errors) Strings() []string {
+	if len(errs) == 0 {
+		return nil
+	}
+	msgs := make([]string, len(errs))
+	for i, err := range errs {
+		msgs[i] = err.Error()
+	}
+	return msgs
+}
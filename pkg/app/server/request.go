@@ -0,0 +1,73 @@
+package server
+
+import (
+	"net/http"
+	"strings"
+)
+
+const (
+	MIMEApplicationJSON = "application/json; charset=utf-8"
+	MIMETextPlain       = "text/plain; charset=utf-8"
+	MIMEOctetStream     = "application/octet-stream"
+)
+
+// MetaAccept Request.Meta 中期望的响应编码类型或编解码器名称，多个时以 , 分隔
+// eg: application/msgpack、cbor、application/cbor, application/json
+const MetaAccept = "Accept"
+
+// Request 一次调用携带的请求数据
+type Request struct {
+	Kind        string            // 操作类型 为空时使用默认的操作类型
+	QueryString []byte            // path 中 ? 之后的查询字符串，由 Engine.Serve 从 path 中分离
+	Body        []byte            // 原始 payload
+	ContentType string            // payload 的编码类型
+	Meta        map[string]string // 前端附带的元数据
+}
+
+// SetMeta 设置一条元数据
+func (req *Request) SetMeta(key, value string) {
+	if req.Meta == nil {
+		req.Meta = make(map[string]string)
+	}
+	req.Meta[key] = value
+}
+
+// GetMeta 返回 key 对应的元数据，不存在时返回空字符串
+func (req *Request) GetMeta(key string) string {
+	return req.Meta[key]
+}
+
+// Response handler 处理后产生的结果
+type Response struct {
+	StatusCode  int      // 状态码 默认 200
+	ContentType string   // 响应体的编码类型
+	Body        []byte   // 响应体
+	Err         error    // handler 报告的错误
+	Location    string   // 路由重定向时的规范路径
+	Allow       []string // 操作类型不允许时，该 path 允许的操作类型
+}
+
+// Written 是否已经通过 Data、String、JSON、Render 等方法写入了响应体
+func (resp *Response) Written() bool {
+	return resp.ContentType != "" || len(resp.Body) > 0
+}
+
+// IsJSON 响应体是否为 JSON
+func (resp *Response) IsJSON() bool {
+	return strings.HasPrefix(resp.ContentType, "application/json")
+}
+
+// IsText 响应体是否为纯文本
+func (resp *Response) IsText() bool {
+	return strings.HasPrefix(resp.ContentType, "text/")
+}
+
+// reset 将响应恢复为初始状态
+func (resp *Response) reset() {
+	resp.StatusCode = http.StatusOK
+	resp.ContentType = ""
+	resp.Body = nil
+	resp.Err = nil
+	resp.Location = ""
+	resp.Allow = nil
+}
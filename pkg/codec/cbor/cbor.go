@@ -0,0 +1,26 @@
+// Package cbor 提供 CBOR 编解码器
+package cbor
+
+import (
+	"github.com/fxamacker/cbor/v2"
+)
+
+// Codec CBOR 编解码器
+// 没有 cbor 标签的字段使用 json 标签，与 JSON 编解码器保持一致
+type Codec struct{}
+
+func (Codec) Name() string {
+	return "cbor"
+}
+
+func (Codec) ContentType() string {
+	return "application/cbor"
+}
+
+func (Codec) Marshal(v interface{}) ([]byte, error) {
+	return cbor.Marshal(v)
+}
+
+func (Codec) Unmarshal(data []byte, v interface{}) error {
+	return cbor.Unmarshal(data, v)
+}
@@ -0,0 +1,117 @@
+// Package codec 定义请求体和响应体的编解码器
+// JSON 编解码器内置，MessagePack 和 CBOR 编解码器位于子包 msgpack 和 cbor 中，需要时注册到 Registry
+package codec
+
+import (
+	"encoding/json"
+	"mime"
+	"strings"
+	"sync"
+)
+
+// Codec 编解码器
+type Codec interface {
+	// Name 编解码器的名称 eg: json
+	Name() string
+	// ContentType 编码后数据的编码类型 eg: application/json; charset=utf-8
+	ContentType() string
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+}
+
+// JSON 内置的 JSON 编解码器，使用 encoding/json
+type JSON struct{}
+
+func (JSON) Name() string {
+	return "json"
+}
+
+func (JSON) ContentType() string {
+	return "application/json; charset=utf-8"
+}
+
+func (JSON) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (JSON) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+// Registry 编解码器注册表，并发安全
+// 可以通过编码类型 (eg: application/msgpack) 或名称 (eg: msgpack) 查找编解码器
+type Registry struct {
+	mu     sync.RWMutex
+	codecs []Codec
+}
+
+// NewRegistry 创建注册表，JSON 编解码器始终存在并作为默认编解码器
+func NewRegistry(codecs ...Codec) *Registry {
+	r := &Registry{codecs: []Codec{JSON{}}}
+	for _, c := range codecs {
+		r.Register(c)
+	}
+	return r
+}
+
+// Register 注册编解码器，名称相同时替换已注册的编解码器
+func (r *Registry) Register(c Codec) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i, old := range r.codecs {
+		if old.Name() == c.Name() {
+			r.codecs[i] = c
+			return
+		}
+	}
+	r.codecs = append(r.codecs, c)
+}
+
+// Default 返回默认编解码器，即名称为 json 的编解码器
+func (r *Registry) Default() Codec {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.codecs[0]
+}
+
+// Lookup 根据编码类型或名称查找编解码器，编码类型的参数会被忽略
+// eg: application/json; charset=utf-8、application/json、json 都会找到 JSON 编解码器
+func (r *Registry) Lookup(contentType string) (Codec, bool) {
+	name := mediaType(contentType)
+	if name == "" {
+		return nil, false
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, c := range r.codecs {
+		if name == c.Name() || name == mediaType(c.ContentType()) {
+			return c, true
+		}
+	}
+	return nil, false
+}
+
+// Negotiate 根据 accept 选择编解码器，accept 可以包含多个以 , 分隔的编码类型或名称
+// 按照出现的顺序返回第一个已注册的编解码器，q 参数被忽略
+// eg: application/cbor, application/json;q=0.9
+func (r *Registry) Negotiate(accept string) (Codec, bool) {
+	for _, part := range strings.Split(accept, ",") {
+		if c, ok := r.Lookup(part); ok {
+			return c, true
+		}
+	}
+	return nil, false
+}
+
+// mediaType 返回去掉参数并转为小写的编码类型
+func mediaType(contentType string) string {
+	if t, _, err := mime.ParseMediaType(contentType); err == nil {
+		return t
+	}
+	t, _, _ := strings.Cut(contentType, ";")
+	return strings.ToLower(strings.TrimSpace(t))
+}
@@ -0,0 +1,81 @@
+package codec_test
+
+import (
+	"github.com/Yuki-J1/wailsrouter/pkg/codec"
+	"github.com/Yuki-J1/wailsrouter/pkg/codec/cbor"
+	"github.com/Yuki-J1/wailsrouter/pkg/codec/msgpack"
+	"github.com/cloudwego/hertz/pkg/common/test/assert"
+	"testing"
+)
+
+type payload struct {
+	ID    int64             `json:"id"`
+	Name  string            `json:"name"`
+	Tags  []string          `json:"tags"`
+	Data  []byte            `json:"data"`
+	Attrs map[string]string `json:"attrs"`
+	Score float64           `json:"score"`
+	Skip  string            `json:"-"`
+}
+
+func TestCodec_RoundTrip(t *testing.T) {
+	in := payload{
+		ID:    42,
+		Name:  "YKJ",
+		Tags:  []string{"a", "b"},
+		Data:  []byte{0, 1, 2, 255},
+		Attrs: map[string]string{"k": "v"},
+		Score: 9.5,
+		Skip:  "skip",
+	}
+	for _, c := range []codec.Codec{codec.JSON{}, msgpack.Codec{}, cbor.Codec{}} {
+		data, err := c.Marshal(in)
+		assert.Nil(t, err)
+		var out payload
+		assert.Nil(t, c.Unmarshal(data, &out))
+		want := in
+		want.Skip = ""
+		assert.DeepEqual(t, want, out)
+
+		// 字段名使用 json 标签
+		var m map[string]interface{}
+		assert.Nil(t, c.Unmarshal(data, &m))
+		assert.DeepEqual(t, "YKJ", m["name"])
+		_, ok := m["Skip"]
+		assert.False(t, ok)
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	r := codec.NewRegistry(msgpack.Codec{})
+	assert.DeepEqual(t, "json", r.Default().Name())
+
+	tests := []struct {
+		contentType string
+		name        string
+	}{
+		{"application/json; charset=utf-8", "json"},
+		{"Application/JSON", "json"},
+		{"json", "json"},
+		{"application/msgpack", "msgpack"},
+		{"msgpack", "msgpack"},
+		{"application/cbor", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		c, ok := r.Lookup(tt.contentType)
+		assert.DeepEqual(t, tt.name != "", ok)
+		if ok {
+			assert.DeepEqual(t, tt.name, c.Name())
+		}
+	}
+
+	c, ok := r.Negotiate("application/cbor, application/msgpack;q=0.9, application/json;q=0.8")
+	assert.True(t, ok)
+	assert.DeepEqual(t, "msgpack", c.Name())
+	r.Register(cbor.Codec{})
+	c, _ = r.Negotiate("application/cbor, application/msgpack;q=0.9")
+	assert.DeepEqual(t, "cbor", c.Name())
+	_, ok = r.Negotiate("text/html")
+	assert.False(t, ok)
+}
@@ -0,0 +1,35 @@
+// Package msgpack 提供 MessagePack 编解码器
+package msgpack
+
+import (
+	"bytes"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// Codec MessagePack 编解码器
+// 字段名使用 json 标签，与 JSON 编解码器保持一致
+type Codec struct{}
+
+func (Codec) Name() string {
+	return "msgpack"
+}
+
+func (Codec) ContentType() string {
+	return "application/msgpack"
+}
+
+func (Codec) Marshal(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := msgpack.NewEncoder(&buf)
+	enc.SetCustomStructTag("json")
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (Codec) Unmarshal(data []byte, v interface{}) error {
+	dec := msgpack.NewDecoder(bytes.NewReader(data))
+	dec.SetCustomStructTag("json")
+	return dec.Decode(v)
+}
@@ -0,0 +1,148 @@
+package route
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// paramConstraint : 参数的类型约束
+// 路由规则中写作 /user/:id<int>、/doc/:uuid<uuid>、/tag/:t<regex:[a-z]+>
+// 参数值不满足约束时 find 不会进入该参数节点，而是继续尝试其他节点
+// 同一位置可以注册约束不同的参数，先按注册顺序尝试有约束的，再尝试没有约束的，最后回溯到 * 节点
+// eg: /u/:id<int>、/u/:name<alpha>、/u/:slug 同时注册时 /u/42、/u/abc、/u/a1 分别匹配这三个路由
+type paramConstraint struct {
+	expr  string // 约束表达式 eg: int、regex:[a-z]+
+	match func(value string) bool
+}
+
+// 内置的约束
+var constraints = map[string]func(value string) bool{
+	"int": func(value string) bool {
+		_, err := strconv.ParseInt(value, 10, 64)
+		return err == nil
+	},
+	"uint": func(value string) bool {
+		_, err := strconv.ParseUint(value, 10, 64)
+		return err == nil
+	},
+	"float": func(value string) bool {
+		_, err := strconv.ParseFloat(value, 64)
+		return err == nil
+	},
+	"bool": func(value string) bool {
+		_, err := strconv.ParseBool(value)
+		return err == nil
+	},
+	"alpha": func(value string) bool {
+		for i := 0; i < len(value); i++ {
+			if c := value[i] | 0x20; c < 'a' || c > 'z' {
+				return false
+			}
+		}
+		return true
+	},
+	"alnum": func(value string) bool {
+		for i := 0; i < len(value); i++ {
+			if c := value[i]; (c < '0' || c > '9') && (c|0x20 < 'a' || c|0x20 > 'z') {
+				return false
+			}
+		}
+		return true
+	},
+	"uuid": isUUID,
+}
+
+// regexPrefix 正则约束的前缀
+const regexPrefix = "regex:"
+
+// newConstraint 根据约束表达式创建约束
+func newConstraint(expr string) (*paramConstraint, error) {
+	if strings.HasPrefix(expr, regexPrefix) {
+		re, err := regexp.Compile("^(?:" + expr[len(regexPrefix):] + ")$")
+		if err != nil {
+			return nil, err
+		}
+		return &paramConstraint{expr: expr, match: re.MatchString}, nil
+	}
+	match, ok := constraints[expr]
+	if !ok {
+		return nil, fmt.Errorf("unknown constraint '%s'", expr)
+	}
+	return &paramConstraint{expr: expr, match: match}, nil
+}
+
+// constraintExpr 返回约束表达式，c 为 nil 时返回空字符串
+func (c *paramConstraint) constraintExpr() string {
+	if c == nil {
+		return nilString
+	}
+	return c.expr
+}
+
+// isUUID 是否为 8-4-4-4-12 格式的 UUID
+func isUUID(value string) bool {
+	if len(value) != 36 {
+		return false
+	}
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		switch i {
+		case 8, 13, 18, 23:
+			if c != '-' {
+				return false
+			}
+		default:
+			if (c < '0' || c > '9') && (c|0x20 < 'a' || c|0x20 > 'f') {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// wildcardEnd 解析 path[i] 处的 : 参数
+// 返回参数名结束的位置和整个参数 (包含约束) 结束的位置
+// 参数名到 / < : * ? 为止，与只支持一个参数时相同 eg: /:user-id 的参数名为 user-id
+// 同一路径段中紧接着还有 : 参数时，参数名只能由字母、数字、下划线、非 ASCII 字符组成，之后的字符作为分隔符
+// eg: /user/:id<int>/x  i 指向 : 时 nameEnd 指向 <  end 指向 /
+// eg: /files/:name.:ext  i 指向第一个 : 时 nameEnd 和 end 都指向 .
+// 约束的 < 没有闭合时 ok 为 false
+func wildcardEnd(path string, i int) (nameEnd, end int, ok bool) {
+	nameEnd = i + 1
+	for ; nameEnd < len(path) && !strings.ContainsRune("/<:*?", rune(path[nameEnd])); nameEnd++ {
+	}
+	// 多个参数 eg: /range/:from-:to
+	if nameEnd < len(path) && path[nameEnd] == paramLabel {
+		strictEnd := i + 1
+		for ; strictEnd < nameEnd && isParamNameChar(path[strictEnd]); strictEnd++ {
+		}
+		nameEnd = strictEnd
+	}
+	if nameEnd == len(path) || path[nameEnd] != '<' {
+		return nameEnd, nameEnd, true
+	}
+	// 找到与 < 对应的 >
+	depth := 0
+	for end = nameEnd; end < len(path); end++ {
+		switch path[end] {
+		case '<':
+			depth++
+		case '>':
+			depth--
+			if depth == 0 {
+				return nameEnd, end + 1, true
+			}
+		case '/':
+			return nameEnd, end, false
+		}
+	}
+	return nameEnd, end, false
+}
+
+// isParamNameChar 是否为多个参数共用一个路径段时参数名允许的字符
+// 非 ASCII 字符的每个字节都大于等于 0x80
+func isParamNameChar(c byte) bool {
+	return c == '_' || (c >= '0' && c <= '9') || (c|0x20 >= 'a' && c|0x20 <= 'z') || c >= 0x80
+}
@@ -1,19 +1,86 @@
 package route
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"github.com/Yuki-J1/wailsrouter/pkg/app/server"
+	"github.com/Yuki-J1/wailsrouter/pkg/codec"
 	"github.com/cloudwego/hertz/pkg/common/utils"
+	"net/http"
+	"net/url"
 	"sync"
+	"sync/atomic"
 )
 
+// RouteInfo 一条已注册路由的信息
+type RouteInfo struct {
+	Kind         string             // 操作类型
+	Path         string             // 注册时的路由规则
+	Handler      string             // 业务 handler 的函数名
+	HandlerFunc  server.HandlerFunc // 业务 handler
+	HandlerCount int                // 调用链中 handler 的个数 (包含中间件)
+	ParamNames   []string           // 路由规则中的参数名
+}
+
+// RoutesInfo 路由信息列表
+type RoutesInfo []RouteInfo
+
 type Engine struct {
 	RouterGroup
-	tree         RadixTree
-	PanicHandler server.HandlerFunc
+	// trees 所有操作类型路由树的快照
+	// 注册路由时复制一份修改后整体替换，Serve 始终读取完整的快照，不会观察到修改了一半的节点
+	trees        atomic.Pointer[KindTrees]
+	mu           sync.RWMutex       // 保护路由树的修改、names 以及根路由组中间件和 NoRoute、NoKind 调用链的修改
+	PanicHandler server.HandlerFunc // 发生未被 Recovery 捕获的 panic 时执行，*PanicError 记录在 ctx.Errors 中，为 nil 时不捕获 panic
 	ctxPool      sync.Pool
 	maxParams    uint16
+
+	// RedirectTrailingSlash 当前路径没有匹配到路由，但去掉或加上尾部斜杠后的路径存在路由时
+	// 不执行 NoRoute，而是将状态码设置为 301，并将规范路径写入 Response.Location
+	// 例如注册了 /user/:name，请求 /user/42/ 时规范路径为 /user/42
+	RedirectTrailingSlash bool
+
+	// RedirectFixedPath 当前路径没有匹配到路由时，先清理路径中多余的 //、.、..
+	// 再不区分大小写地查找，找到后与 RedirectTrailingSlash 相同，将规范路径写入 Response.Location
+	// 例如注册了 /user/:name，请求 /USER//42 时规范路径为 /user/42
+	RedirectFixedPath bool
+
+	// UseRawPath 为 true 时使用未解码的原始 path 查找路由，参数值是否解码由 UnescapePathValues 决定
+	// 为 false 时先对 path 进行百分号解码再查找，参数值自然也是解码后的
+	UseRawPath bool
+
+	// UnescapePathValues 在 UseRawPath 为 true 时对参数值进行百分号解码，默认为 true
+	// 例如注册了 /files/*path，请求 /files/my%20docs/%E6%96%87%E4%BB%B6 时参数值为 my docs/文件
+	UnescapePathValues bool
+
+	// ForwardRedirect 需要重定向时不返回 301，而是直接以规范路径分发到对应路由
+	// 对 RedirectTrailingSlash 和 RedirectFixedPath 均生效，规范路径同样写入 Response.Location
+	ForwardRedirect bool
+
+	noRoute    server.HandlersChain                 // NoRoute 注册的 handler
+	allNoRoute atomic.Pointer[server.HandlersChain] // 根路由组中间件 + noRoute，与路由树一样整体替换
+	names      map[string]*Route                    // 已命名的路由
+	noKind     server.HandlersChain                 // NoKind 注册的 handler
+	allNoKind  atomic.Pointer[server.HandlersChain] // 根路由组中间件 + noKind，与路由树一样整体替换
+
+	// HandleKindNotAllowed 当前操作类型没有匹配到路由，但其他操作类型下存在该 path 的路由时
+	// 不执行 NoRoute，而是将状态码设置为 405，记录 ErrKindNotAllowed 错误，并将允许的操作类型写入 Response.Allow
+	HandleKindNotAllowed bool
+
+	// Codecs 请求体和响应体的编解码器，默认只有 JSON
+	// ctx.Bind 根据 Request.ContentType 选择编解码器解析请求体
+	// ctx.Render 根据 Request.Meta 中的 Accept 或 Request.ContentType 选择编解码器编码响应体
+	// 例如 engine.Codecs.Register(msgpack.Codec{}) 后前端可以以 application/msgpack 传递 payload
+	// 请求上下文在创建时获取 Codecs，因此替换 Codecs 需要在处理请求之前
+	Codecs *codec.Registry
+
+	// ErrorHandler 请求处理完成后 ctx.Errors 不为空，并且 handler 没有写入响应体时执行，用于将错误渲染为统一的响应体
+	// NoRoute、NoKind、PanicHandler 等已经写入的响应不会被覆盖，Typed 失败时只记录错误，由 ErrorHandler 渲染
+	// 没有匹配到路由、操作类型不允许、参数错误、panic 等错误都会记录在 ctx.Errors 中
+	// 默认为 nil，可以设置为 DefaultErrorHandler，为 nil 时 Typed 记录的错误以 AsError 转换后的 *Error 写入响应体
+	ErrorHandler server.HandlerFunc
 }
 
 func NewEngine() *Engine {
@@ -23,10 +90,16 @@ func NewEngine() *Engine {
 			basePath: "/",  // 根路由组  basePath default is "/"
 			root:     true, // 表示是否为根路由组
 		},
-		tree:      RadixTree{root: &node{}},
-		maxParams: 64,
+		names:                 make(map[string]*Route),
+		maxParams:             64,
+		RedirectTrailingSlash: true,
+		UnescapePathValues:    true,
+		Codecs:                codec.NewRegistry(),
 	}
 	engine.RouterGroup.engine = engine
+	engine.trees.Store(&KindTrees{})
+	engine.rebuildNoRouteHandlers()
+	engine.rebuildNoKindHandlers()
 	engine.ctxPool.New = func() interface{} {
 		ctx := engine.NewContext()
 		return ctx
@@ -35,58 +108,442 @@ func NewEngine() *Engine {
 }
 
 func (engine *Engine) NewContext() *server.RequestContext {
-	return server.NewContext(engine.maxParams)
+	ctx := server.NewContext(engine.maxParams)
+	ctx.SetCodecs(engine.Codecs)
+	return ctx
+}
+
+// AcquireContext 从 ctxPool 中获取一个已重置的请求上下文
+// 使用完毕后需要调用 ReleaseContext 放回
+func (engine *Engine) AcquireContext() *server.RequestContext {
+	ctx := engine.ctxPool.Get().(*server.RequestContext)
+	ctx.Reset()
+	return ctx
+}
+
+// ReleaseContext 重置请求上下文并放回 ctxPool
+// 放回后 ctx 不能再被使用，需要在 handler 返回后继续使用的请用 ctx.Copy()
+func (engine *Engine) ReleaseContext(ctx *server.RequestContext) {
+	ctx.Reset()
+	engine.ctxPool.Put(ctx)
+}
+
+// Use 向根路由组添加中间件，中间件同样作用于 NoRoute 和 NoKind
+func (engine *Engine) Use(middleware ...server.HandlerFunc) IRoutes {
+	engine.mu.Lock()
+	defer engine.mu.Unlock()
+	engine.RouterGroup.Use(middleware...)
+	engine.rebuildNoRouteHandlers()
+	engine.rebuildNoKindHandlers()
+	return engine
+}
+
+// NoRoute 设置没有匹配到路由时执行的 handler
+// 执行前请求上下文的状态码被设置为 404，并记录 ErrNoRoute 错误
+func (engine *Engine) NoRoute(handlers ...server.HandlerFunc) {
+	engine.mu.Lock()
+	defer engine.mu.Unlock()
+	engine.noRoute = handlers
+	engine.rebuildNoRouteHandlers()
+}
+
+// NoKind 设置操作类型不允许时执行的 handler，需要开启 HandleKindNotAllowed
+// 执行前请求上下文的状态码被设置为 405，并记录 ErrKindNotAllowed 错误
+func (engine *Engine) NoKind(handlers ...server.HandlerFunc) {
+	engine.mu.Lock()
+	defer engine.mu.Unlock()
+	engine.noKind = handlers
+	engine.rebuildNoKindHandlers()
+}
+
+// rebuildNoRouteHandlers 重新组合并发布 NoRoute 的调用链，需要持有 mu
+func (engine *Engine) rebuildNoRouteHandlers() {
+	allNoRoute := engine.combineHandlers(engine.noRoute)
+	engine.allNoRoute.Store(&allNoRoute)
 }
 
-// addRoute 直接通过 func (r *RadixTree) addRoute 添加路由
-func (engine *Engine) addRoute(path string, handlers server.HandlersChain) {
-	// path必须不为空 否则panic
+// rebuildNoKindHandlers 重新组合并发布 NoKind 的调用链，需要持有 mu
+func (engine *Engine) rebuildNoKindHandlers() {
+	allNoKind := engine.combineHandlers(engine.noKind)
+	engine.allNoKind.Store(&allNoKind)
+}
+
+// Routes 返回所有已注册的路由信息
+// 同一操作类型下按照 static > param > any 的查找顺序排列，可选参数路由只出现一次
+func (engine *Engine) Routes() (routes RoutesInfo) {
+	for _, tree := range engine.loadTrees() {
+		routes = iterate(tree.kind, routes, tree.root)
+	}
+	return dedupOptional(routes)
+}
+
+// dedupOptional 可选参数展开的节点共用 ppath，只保留参数完整的那一个
+func dedupOptional(routes RoutesInfo) RoutesInfo {
+	seen := make(map[[2]string]int, len(routes))
+	n := 0
+	for _, r := range routes {
+		key := [2]string{r.Kind, r.Path}
+		if i, ok := seen[key]; ok {
+			if len(r.ParamNames) > len(routes[i].ParamNames) {
+				routes[i] = r
+			}
+			continue
+		}
+		seen[key] = n
+		routes[n] = r
+		n++
+	}
+	return routes[:n]
+}
+
+// iterate 深度优先遍历 root，收集有 handler 的节点
+func iterate(kind string, routes RoutesInfo, root *node) RoutesInfo {
+	if len(root.handlers) > 0 {
+		handlerFunc := root.handlers.Last()
+		routes = append(routes, RouteInfo{
+			Kind:         kind,
+			Path:         root.ppath,
+			Handler:      utils.NameOfFunction(handlerFunc),
+			HandlerFunc:  handlerFunc,
+			HandlerCount: len(root.handlers),
+			ParamNames:   append([]string(nil), root.pnames...),
+		})
+	}
+
+	for _, child := range root.children {
+		routes = iterate(kind, routes, child)
+	}
+	for _, child := range root.paramChildren {
+		routes = iterate(kind, routes, child)
+	}
+	if root.anyChild != nil {
+		routes = iterate(kind, routes, root.anyChild)
+	}
+	return routes
+}
+
+// addRoute 添加路由，失败时 panic
+func (engine *Engine) addRoute(kind, path string, handlers server.HandlersChain, middlewares int) {
+	if err := engine.tryAddRoute(kind, path, handlers, middlewares); err != nil {
+		panic(err.Error())
+	}
+}
+
+// tryAddRoute 直接通过 func (r *RadixTree) tryAddRoute 添加路由，失败时返回 *RouteError
+// middlewares 为 handlers 中路由组中间件的个数，Replace 时保留
+// 每种操作类型对应一棵 RadixTree，不存在时创建
+func (engine *Engine) tryAddRoute(kind, path string, handlers server.HandlersChain, middlewares int) error {
+	// kind必须不为空
+	if kind == "" {
+		return newRouteError(ErrInvalidPath, path, "", "kind should not be ''")
+	}
+	// path必须不为空
 	if len(path) == 0 {
-		panic("path should not be ''")
+		return newRouteError(ErrInvalidPath, path, "", "path should not be ''")
+	}
+	// path必须/字符开头
+	if path[0] != '/' {
+		return newRouteError(ErrInvalidPath, path, "", "path must begin with '/'")
 	}
-	// path必须/字符开头 否则panic
-	utils.Assert(path[0] == '/', "path must begin with '/'")
 	// handlers必须不能为空 handlers
-	utils.Assert(len(handlers) > 0, "there must be at least one handler")
+	if len(handlers) == 0 {
+		return newRouteError(ErrNoHandler, path, "", "there must be at least one handler")
+	}
 
 	// 添加路由
-	engine.tree.addRoute(path, handlers)
+	return engine.updateTree(kind, func(tree *RadixTree) error {
+		return tree.tryAddRoute(path, handlers, middlewares)
+	})
+}
+
+// Remove 删除所有操作类型下以 path 注册的路由，以及指向这些路由的名称
+// path 需要与注册时的完整路由规则一致，例如 /v1/user/:name
+// 所有操作类型的修改一次性生效，路由不存在时返回 ErrRouteNotFound
+func (engine *Engine) Remove(path string) error {
+	return engine.updateTrees(func(trees KindTrees) error {
+		var removed []string
+		for _, tree := range trees {
+			if tree.remove(path) {
+				removed = append(removed, tree.kind)
+			}
+		}
+		if len(removed) == 0 {
+			return fmt.Errorf("%w: '%s'", ErrRouteNotFound, path)
+		}
+
+		for name, r := range engine.names {
+			if r.path != path {
+				continue
+			}
+			for _, kind := range removed {
+				if r.kind == kind {
+					delete(engine.names, name)
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// Replace 替换所有操作类型下以 path 注册的路由的 handlers
+// 注册时所在路由组的中间件 (包括根路由组) 会保留，只替换之后的 handlers
+// 所有操作类型的修改一次性生效，任意一个失败时都不会替换
+// 路由不存在时返回 ErrRouteNotFound
+func (engine *Engine) Replace(path string, handlers ...server.HandlerFunc) error {
+	if len(handlers) == 0 {
+		return newRouteError(ErrNoHandler, path, "", "there must be at least one handler")
+	}
+
+	return engine.updateTrees(func(trees KindTrees) error {
+		replaced := false
+		for _, tree := range trees {
+			err := tree.replace(path, handlers)
+			if errors.Is(err, ErrRouteNotFound) {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			replaced = true
+		}
+		if !replaced {
+			return fmt.Errorf("%w: '%s'", ErrRouteNotFound, path)
+		}
+		return nil
+	})
 }
 
+// loadTrees 返回当前路由树的快照
+func (engine *Engine) loadTrees() KindTrees {
+	return *engine.trees.Load()
+}
+
+// updateTree 复制 kind 对应的路由树后交给 update 修改，成功后整体替换路由树快照
+// update 失败时丢弃副本，已有路由不受影响
+// 每种操作类型对应一棵 RadixTree，不存在时创建
+func (engine *Engine) updateTree(kind string, update func(tree *RadixTree) error) error {
+	engine.mu.Lock()
+	defer engine.mu.Unlock()
+
+	trees := engine.copyTrees()
+	tree := trees.get(kind)
+	if tree == nil {
+		tree = &RadixTree{kind: kind, root: &node{}}
+		trees = append(trees, tree)
+	}
+	if err := update(tree); err != nil {
+		return err
+	}
+	engine.trees.Store(&trees)
+	return nil
+}
+
+// updateTrees 复制所有操作类型的路由树后交给 update 修改，成功后以一次替换发布所有修改
+// update 在持有 mu 时执行，失败时丢弃所有副本，已有路由不受影响
+func (engine *Engine) updateTrees(update func(trees KindTrees) error) error {
+	engine.mu.Lock()
+	defer engine.mu.Unlock()
+
+	trees := engine.copyTrees()
+	if err := update(trees); err != nil {
+		return err
+	}
+	engine.trees.Store(&trees)
+	return nil
+}
+
+// copyTrees 复制当前快照中的所有路由树，需要持有 mu
+// 副本与原树共享节点，RadixTree 的修改只复制经过的节点，原树不受影响
+func (engine *Engine) copyTrees() KindTrees {
+	trees := engine.loadTrees()
+	cp := make(KindTrees, len(trees), len(trees)+1)
+	for i, tree := range trees {
+		cp[i] = &RadixTree{kind: tree.kind, root: tree.root}
+	}
+	return cp
+}
+
+// Serve 分发请求，处理完成后存在错误时执行 ErrorHandler
+// PanicHandler 为 nil 时 Serve 不会捕获 panic，需要通过 Recovery 中间件捕获
 func (engine *Engine) Serve(c context.Context, ctx *server.RequestContext) {
+	handlingErrors := false
 	// 用于防止服务器因未恢复的恐慌而崩溃。
 	if engine.PanicHandler != nil {
-		defer engine.recv(ctx)
+		defer func() {
+			if rcv := recover(); rcv != nil {
+				engine.recv(c, ctx, rcv, handlingErrors)
+			}
+		}()
+	}
+	engine.serve(c, ctx)
+	handlingErrors = true
+	engine.handleErrors(c, ctx)
+}
+
+// serve 查找路由并执行对应的 handler
+func (engine *Engine) serve(c context.Context, ctx *server.RequestContext) {
+	// 查询字符串不参与路由匹配 eg: /search?q=go > /search
+	if i := bytes.IndexByte(ctx.Path, '?'); i != -1 {
+		ctx.SetQueryString(ctx.Path[i+1:])
+		// SetQueryString 会复制查询字符串，Path 保留原有容量供池化的请求上下文复用
+		ctx.Path = ctx.Path[:i]
 	}
 
 	// path
 	rPath := string(ctx.Path)
+	unescape := false
+	if engine.UseRawPath {
+		unescape = engine.UnescapePathValues
+	} else if p, err := url.PathUnescape(rPath); err == nil {
+		rPath = p
+	}
+
+	// 操作类型
+	kind := ctx.Request.Kind
+	if kind == "" {
+		kind = KindQuery
+	}
+
+	// 本次请求始终使用同一个快照
+	trees := engine.loadTrees()
+	if tree := trees.get(kind); tree != nil {
+		// 查询
+		paramsPointer := &ctx.Params
+		value := tree.find(rPath, paramsPointer, unescape)
+		if value.handlers != nil {
+			engine.dispatch(c, ctx, value)
+			return
+		}
+
+		// 仅尾部斜杠不同
+		if value.tsr && engine.RedirectTrailingSlash {
+			engine.redirect(c, ctx, tree, trailingSlashPath(rPath), unescape)
+			return
+		}
 
-	// 查询
-	paramsPointer := &ctx.Params
-	value := engine.tree.find(rPath, paramsPointer, false)
+		// 清理路径后不区分大小写查找
+		if engine.RedirectFixedPath {
+			if fixedPath, ok := tree.findCaseInsensitivePath(utils.CleanPath(rPath), engine.RedirectTrailingSlash); ok {
+				engine.redirect(c, ctx, tree, fixedPath, unescape)
+				return
+			}
+		}
+	}
+
+	// 其他操作类型下存在该 path
+	if engine.HandleKindNotAllowed {
+		if allow := trees.allowedKinds(kind, rPath, &ctx.Params, unescape); len(allow) > 0 {
+			engine.handleNoKind(c, ctx, allow)
+			return
+		}
+	}
+
+	engine.handleNoRoute(c, ctx)
+}
+
+// allowedKinds 返回除 kind 以外，注册了 path 的操作类型
+func (trees KindTrees) allowedKinds(kind, path string, paramsPointer *server.Params, unescape bool) (allow []string) {
+	for _, tree := range trees {
+		if tree.kind == kind {
+			continue
+		}
+		*paramsPointer = (*paramsPointer)[:0]
+		if value := tree.find(path, paramsPointer, unescape); value.handlers != nil {
+			allow = append(allow, tree.kind)
+		}
+	}
+	*paramsPointer = (*paramsPointer)[:0]
+	return
+}
+
+// dispatch 执行查找到的路由
+func (engine *Engine) dispatch(c context.Context, ctx *server.RequestContext, value nodeValue) {
+	// 为请求上下文设置handlers
+	ctx.SetHandlers(value.handlers)
+	// 为请求上下文设置path
+	ctx.SetFullPath(value.fullPath)
+	// 开始进入洋葱
+	ctx.Next(c)
+}
+
+// redirect 将规范路径写入 Response.Location
+// 开启 ForwardRedirect 时直接以规范路径分发，否则设置 301 状态码
+// canonicalPath 与查找使用的 path 相同，未开启 UseRawPath 时是解码后的，写入 Location 前重新编码
+// eg: 请求 /f/%2541/ 时 Location 为 /f/%2541 而不是 /f/%41，再次请求时参数值仍为 %41
+func (engine *Engine) redirect(c context.Context, ctx *server.RequestContext, tree *RadixTree, canonicalPath string, unescape bool) {
+	ctx.Response.Location = canonicalPath
+	if !engine.UseRawPath {
+		ctx.Response.Location = (&url.URL{Path: canonicalPath}).EscapedPath()
+	}
+	if len(ctx.Request.QueryString) > 0 {
+		ctx.Response.Location += "?" + string(ctx.Request.QueryString)
+	}
+	if !engine.ForwardRedirect {
+		ctx.Status(http.StatusMovedPermanently)
+		return
+	}
+
+	// 清除上次查找留下的参数
+	ctx.Params = ctx.Params[:0]
+	value := tree.find(canonicalPath, &ctx.Params, unescape)
 	if value.handlers != nil {
-		// 为请求上下文设置handlers
-		ctx.SetHandlers(value.handlers)
-		// 为请求上下文设置path
-		ctx.SetFullPath(value.fullPath)
-		// 开始进入洋葱
-		ctx.Next(c)
+		engine.dispatch(c, ctx, value)
 		return
 	}
+	engine.handleNoRoute(c, ctx)
+}
+
+// trailingSlashPath 去掉或加上 path 的尾部斜杠
+func trailingSlashPath(path string) string {
+	if len(path) > 1 && path[len(path)-1] == '/' {
+		return path[:len(path)-1]
+	}
+	return path + "/"
+}
+
+// ServePath 以 JSON payload 分发 path，返回 handler 产生的响应
+func (engine *Engine) ServePath(c context.Context, path string, payload []byte) server.Response {
+	return engine.ServeRequest(c, path, server.Request{
+		Body:        payload,
+		ContentType: server.MIMEApplicationJSON,
+	})
+}
+
+// ServeRequest 从 ctxPool 获取请求上下文，分发 path 后释放，返回 handler 产生的响应
+func (engine *Engine) ServeRequest(c context.Context, path string, req server.Request) server.Response {
+	ctx := engine.AcquireContext()
+	defer engine.ReleaseContext(ctx)
+
+	ctx.Path = append(ctx.Path, path...)
+	ctx.Request = req
+	engine.Serve(c, ctx)
+	return ctx.Response
+}
 
-	defaultError(c, ctx)
+// handleNoKind 操作类型不允许时，执行根路由组中间件和 NoKind 注册的 handler
+func (engine *Engine) handleNoKind(c context.Context, ctx *server.RequestContext, allow []string) {
+	ctx.Status(http.StatusMethodNotAllowed)
+	ctx.Error(ErrKindNotAllowed)
+	ctx.Response.Allow = allow
+	ctx.SetHandlers(*engine.allNoKind.Load())
+	ctx.Next(c)
 }
 
-func (engine *Engine) recv(ctx *server.RequestContext) {
-	// 如果捕获到panic, 使用PanicHandler处理
-	if rcv := recover(); rcv != nil {
-		engine.PanicHandler(context.Background(), ctx)
+// recv 与 Recovery 相同地记录 panic 后，以原请求的 c 执行 PanicHandler 和 ErrorHandler
+// panic 发生在 ErrorHandler 中时不再执行 ErrorHandler，避免同样的 panic 逃逸出 Serve
+func (engine *Engine) recv(c context.Context, ctx *server.RequestContext, rcv interface{}, handlingErrors bool) {
+	recoverPanic(c, ctx, rcv)
+	engine.PanicHandler(c, ctx)
+	if !handlingErrors {
+		engine.handleErrors(c, ctx)
 	}
 }
 
-// defaultError 默认错误处理
-func defaultError(c context.Context, ctx *server.RequestContext) {
-	//	返回路由不到
-	fmt.Print("没找到")
+// handleNoRoute 没有匹配到路由时，执行根路由组中间件和 NoRoute 注册的 handler
+// 调用方可以通过 404 状态码和 ErrNoRoute 区分"没有路由"和"handler 没有返回内容"
+func (engine *Engine) handleNoRoute(c context.Context, ctx *server.RequestContext) {
+	ctx.Status(http.StatusNotFound)
+	ctx.Error(ErrNoRoute)
+	ctx.SetHandlers(*engine.allNoRoute.Load())
+	ctx.Next(c)
 }
@@ -1,11 +1,19 @@
 package route
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"github.com/Yuki-J1/wailsrouter/pkg/app/server"
+	"github.com/Yuki-J1/wailsrouter/pkg/codec"
+	"github.com/Yuki-J1/wailsrouter/pkg/codec/cbor"
+	"github.com/Yuki-J1/wailsrouter/pkg/codec/msgpack"
 	"github.com/cloudwego/hertz/pkg/common/test/assert"
+	"net/http"
+	"sync"
 	"testing"
-	"wailsrouter/pkg/app/server"
 )
 
 func TestNew_Engine(t *testing.T) {
@@ -17,19 +25,1227 @@ func TestNew_Engine(t *testing.T) {
 
 func TestEngine_Routes(t *testing.T) {
 	de := NewEngine()
-	de.handle("/", server.HandlersChain{HandlerTest1})
-	de.handle("/user/:name", server.HandlersChain{HandlerTest2})
+	de.handle(KindQuery, "/", server.HandlersChain{HandlerTest1})
+	de.handle(KindQuery, "/user/:name", server.HandlersChain{HandlerTest2})
 
 	v1group := de.Group("v1")
 	{
-		v1group.handle("/user", server.HandlersChain{HandlerTest1})
-		v1group.handle("/login", server.HandlersChain{HandlerTest2})
+		v1group.handle(KindQuery, "/user", server.HandlersChain{HandlerTest1})
+		v1group.handle(KindQuery, "/login", server.HandlersChain{HandlerTest2})
 	}
 
 	requestCtx := de.NewContext()
 	requestCtx.Path = []byte("/user/YKJ")
 	de.Serve(context.Background(), requestCtx)
 }
+func TestEngine_Abort(t *testing.T) {
+	de := NewEngine()
+	var trace []string
+	guard := func(c context.Context, ctx *server.RequestContext) {
+		trace = append(trace, "guard")
+		if ctx.Params.ByName("name") != "admin" {
+			ctx.AbortWithError(http.StatusUnauthorized, errors.New("unauthorized"))
+			return
+		}
+		ctx.Next(c)
+		trace = append(trace, "guard done")
+	}
+	de.Use(guard)
+	de.Handle("/user/:name", func(c context.Context, ctx *server.RequestContext) {
+		trace = append(trace, "handler")
+	})
+
+	ctx := de.NewContext()
+	ctx.Path = []byte("/user/guest")
+	de.Serve(context.Background(), ctx)
+	assert.True(t, ctx.IsAborted())
+	assert.DeepEqual(t, []string{"guard"}, trace)
+	assert.DeepEqual(t, http.StatusUnauthorized, ctx.Response.StatusCode)
+	assert.DeepEqual(t, "unauthorized", ctx.Response.Err.Error())
+
+	trace = nil
+	ctx = de.NewContext()
+	ctx.Path = []byte("/user/admin")
+	de.Serve(context.Background(), ctx)
+	assert.False(t, ctx.IsAborted())
+	assert.DeepEqual(t, []string{"guard", "handler", "guard done"}, trace)
+	assert.DeepEqual(t, http.StatusOK, ctx.Response.StatusCode)
+}
+
+func TestEngine_ServeReuseContext(t *testing.T) {
+	de := NewEngine()
+	de.Handle("/user/:name", func(c context.Context, ctx *server.RequestContext) {
+		ctx.String(http.StatusOK, ctx.Params.ByName("name"))
+	})
+
+	ctx := de.NewContext()
+	ctx.Path = []byte("/user/a")
+	de.Serve(context.Background(), ctx)
+	assert.DeepEqual(t, "a", string(ctx.Response.Body))
+	assert.DeepEqual(t, "/user/:name", ctx.FullPath())
+
+	ctx.Reset()
+	assert.DeepEqual(t, 0, len(ctx.Params))
+	assert.DeepEqual(t, "", ctx.FullPath())
+	assert.Nil(t, ctx.Response.Body)
+
+	ctx.Path = append(ctx.Path, "/user/b"...)
+	de.Serve(context.Background(), ctx)
+	assert.DeepEqual(t, "b", string(ctx.Response.Body))
+}
+
+func TestEngine_ServePath(t *testing.T) {
+	de := NewEngine()
+	copied := make(chan *server.RequestContext, 1)
+	de.Handle("/user/:name", func(c context.Context, ctx *server.RequestContext) {
+		copied <- ctx.Copy()
+		ctx.Data(http.StatusCreated, ctx.Request.ContentType, ctx.Request.Body)
+	})
+
+	resp := de.ServePath(context.Background(), "/user/a", []byte(`{"a":1}`))
+	assert.DeepEqual(t, http.StatusCreated, resp.StatusCode)
+	assert.DeepEqual(t, `{"a":1}`, string(resp.Body))
+	assert.DeepEqual(t, server.MIMEApplicationJSON, resp.ContentType)
+
+	cp := <-copied
+	assert.DeepEqual(t, "a", cp.Params.ByName("name"))
+	assert.DeepEqual(t, "/user/a", string(cp.Path))
+	assert.True(t, cp.IsAborted())
+
+	// 池中的请求上下文被复用后 副本不受影响
+	resp = de.ServePath(context.Background(), "/user/b", nil)
+	assert.DeepEqual(t, http.StatusCreated, resp.StatusCode)
+	assert.DeepEqual(t, "a", cp.Params.ByName("name"))
+	assert.DeepEqual(t, "b", (<-copied).Params.ByName("name"))
+}
+
+func TestEngine_NoRoute(t *testing.T) {
+	de := NewEngine()
+	de.Handle("/empty", func(c context.Context, ctx *server.RequestContext) {})
+
+	// 未设置 NoRoute
+	resp := de.ServePath(context.Background(), "/missing", nil)
+	assert.DeepEqual(t, http.StatusNotFound, resp.StatusCode)
+	assert.True(t, errors.Is(resp.Err, ErrNoRoute))
+
+	// handler 没有返回内容
+	resp = de.ServePath(context.Background(), "/empty", nil)
+	assert.DeepEqual(t, http.StatusOK, resp.StatusCode)
+	assert.Nil(t, resp.Err)
+
+	var trace []string
+	de.Use(func(c context.Context, ctx *server.RequestContext) {
+		trace = append(trace, "middleware")
+	})
+	de.NoRoute(func(c context.Context, ctx *server.RequestContext) {
+		trace = append(trace, "noRoute")
+		ctx.String(ctx.Response.StatusCode, "%s not found", ctx.Path)
+	})
+	resp = de.ServePath(context.Background(), "/missing", nil)
+	assert.DeepEqual(t, []string{"middleware", "noRoute"}, trace)
+	assert.DeepEqual(t, http.StatusNotFound, resp.StatusCode)
+	assert.DeepEqual(t, "/missing not found", string(resp.Body))
+	assert.True(t, errors.Is(resp.Err, ErrNoRoute))
+}
+
+func TestEngine_ConcurrentNoRoute(t *testing.T) {
+	de := NewEngine()
+
+	const n = 100
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < n; i++ {
+			if i%20 == 0 {
+				de.Use(func(c context.Context, ctx *server.RequestContext) {})
+			}
+			de.NoRoute(func(c context.Context, ctx *server.RequestContext) {
+				ctx.String(ctx.Response.StatusCode, "not found")
+			})
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < n; i++ {
+			resp := de.ServePath(context.Background(), "/missing", nil)
+			if resp.StatusCode != http.StatusNotFound {
+				t.Errorf("unexpected status: %d", resp.StatusCode)
+			}
+		}
+	}()
+	wg.Wait()
+
+	resp := de.ServePath(context.Background(), "/missing", nil)
+	assert.DeepEqual(t, "not found", string(resp.Body))
+}
+
+func TestEngine_RedirectTrailingSlash(t *testing.T) {
+	de := NewEngine()
+	de.Handle("/user/:name", func(c context.Context, ctx *server.RequestContext) {
+		ctx.String(http.StatusOK, ctx.Params.ByName("name"))
+	})
+	de.Handle("/doc/", func(c context.Context, ctx *server.RequestContext) {
+		ctx.String(http.StatusOK, "doc")
+	})
+
+	tests := []struct {
+		path     string
+		location string
+	}{
+		{"/user/42/", "/user/42"},
+		{"/doc", "/doc/"},
+	}
+	for _, tt := range tests {
+		resp := de.ServePath(context.Background(), tt.path, nil)
+		assert.DeepEqual(t, http.StatusMovedPermanently, resp.StatusCode)
+		assert.DeepEqual(t, tt.location, resp.Location)
+		assert.Nil(t, resp.Body)
+	}
+
+	de.ForwardRedirect = true
+	resp := de.ServePath(context.Background(), "/user/42/", nil)
+	assert.DeepEqual(t, http.StatusOK, resp.StatusCode)
+	assert.DeepEqual(t, "/user/42", resp.Location)
+	assert.DeepEqual(t, "42", string(resp.Body))
+
+	resp = de.ServePath(context.Background(), "/doc", nil)
+	assert.DeepEqual(t, "/doc/", resp.Location)
+	assert.DeepEqual(t, "doc", string(resp.Body))
+
+	// 关闭后走 NoRoute
+	de.RedirectTrailingSlash = false
+	resp = de.ServePath(context.Background(), "/user/42/", nil)
+	assert.DeepEqual(t, http.StatusNotFound, resp.StatusCode)
+	assert.DeepEqual(t, "", resp.Location)
+}
+
+func TestEngine_RedirectFixedPath(t *testing.T) {
+	de := NewEngine()
+	de.Handle("/user/:name", func(c context.Context, ctx *server.RequestContext) {
+		ctx.String(http.StatusOK, ctx.Params.ByName("name"))
+	})
+	de.Handle("/doc/go1.html", func(c context.Context, ctx *server.RequestContext) {
+		ctx.String(http.StatusOK, "doc")
+	})
+
+	// 默认关闭
+	resp := de.ServePath(context.Background(), "/USER/YKJ", nil)
+	assert.DeepEqual(t, http.StatusNotFound, resp.StatusCode)
+
+	de.RedirectFixedPath = true
+	tests := []struct {
+		path     string
+		location string
+	}{
+		{"/USER/YKJ", "/user/YKJ"},
+		{"//user//YKJ", "/user/YKJ"},
+		{"/doc/../user/./YKJ/", "/user/YKJ"},
+		{"/DOC/GO1.HTML", "/doc/go1.html"},
+		{"doc/./go1.html", "/doc/go1.html"},
+	}
+	for _, tt := range tests {
+		resp = de.ServePath(context.Background(), tt.path, nil)
+		assert.DeepEqual(t, http.StatusMovedPermanently, resp.StatusCode)
+		assert.DeepEqual(t, tt.location, resp.Location)
+	}
+
+	de.ForwardRedirect = true
+	resp = de.ServePath(context.Background(), "/User//YKJ", nil)
+	assert.DeepEqual(t, http.StatusOK, resp.StatusCode)
+	assert.DeepEqual(t, "/user/YKJ", resp.Location)
+	assert.DeepEqual(t, "YKJ", string(resp.Body))
+
+	resp = de.ServePath(context.Background(), "/missing/../../x", nil)
+	assert.DeepEqual(t, http.StatusNotFound, resp.StatusCode)
+}
+
+func TestEngine_UnescapePathValues(t *testing.T) {
+	de := NewEngine()
+	de.Handle("/files/*path", func(c context.Context, ctx *server.RequestContext) {
+		ctx.String(http.StatusOK, ctx.Params.ByName("path"))
+	})
+	de.Handle("/user/:name", func(c context.Context, ctx *server.RequestContext) {
+		ctx.String(http.StatusOK, ctx.Params.ByName("name"))
+	})
+
+	tests := []struct {
+		useRawPath bool
+		unescape   bool
+		path       string
+		code       int
+		value      string
+	}{
+		// 默认先解码 path 再查找
+		{false, true, "/files/my%20docs/%E6%96%87%E4%BB%B6", http.StatusOK, "my docs/文件"},
+		{false, true, "/user/a%2Fb", http.StatusNotFound, ""},
+		{false, true, "/user/a+b", http.StatusOK, "a+b"},
+		// 使用原始 path 查找 参数值解码
+		{true, true, "/files/my%20docs/%E6%96%87%E4%BB%B6", http.StatusOK, "my docs/文件"},
+		{true, true, "/user/a%2Fb", http.StatusOK, "a/b"},
+		// 与默认模式相同 + 不会被解码为空格
+		{true, true, "/user/a+b", http.StatusOK, "a+b"},
+		// 使用原始 path 查找 参数值不解码
+		{true, false, "/files/my%20docs", http.StatusOK, "my%20docs"},
+		{true, false, "/user/a%2Fb", http.StatusOK, "a%2Fb"},
+	}
+	for _, tt := range tests {
+		de.UseRawPath = tt.useRawPath
+		de.UnescapePathValues = tt.unescape
+		resp := de.ServePath(context.Background(), tt.path, nil)
+		assert.DeepEqual(t, tt.code, resp.StatusCode)
+		if tt.code == http.StatusOK {
+			assert.DeepEqual(t, tt.value, string(resp.Body))
+		}
+	}
+
+	// 重定向的 path 与请求相同，跟随重定向后参数值不会被再次解码
+	redirects := []struct {
+		useRawPath bool
+		path       string
+		location   string
+		value      string
+	}{
+		{false, "/user/%2541/", "/user/%2541", "%41"},
+		{false, "/user/my%20docs/", "/user/my%20docs", "my docs"},
+		{false, "/USER/%E6%96%87%E4%BB%B6", "/user/%E6%96%87%E4%BB%B6", "文件"},
+		{true, "/user/%2541/", "/user/%2541", "%41"},
+		{true, "/USER/%E6%96%87%E4%BB%B6", "/user/%E6%96%87%E4%BB%B6", "文件"},
+	}
+	de.RedirectFixedPath = true
+	de.UnescapePathValues = true
+	for _, tt := range redirects {
+		de.UseRawPath = tt.useRawPath
+		resp := de.ServePath(context.Background(), tt.path, nil)
+		assert.DeepEqual(t, http.StatusMovedPermanently, resp.StatusCode)
+		assert.DeepEqual(t, tt.location, resp.Location)
+		resp = de.ServePath(context.Background(), resp.Location, nil)
+		assert.DeepEqual(t, http.StatusOK, resp.StatusCode)
+		assert.DeepEqual(t, tt.value, string(resp.Body))
+	}
+}
+
+func TestEngine_Kind(t *testing.T) {
+	de := NewEngine()
+	handler := func(kind string) server.HandlerFunc {
+		return func(c context.Context, ctx *server.RequestContext) {
+			ctx.String(http.StatusOK, kind)
+		}
+	}
+	de.Query("/user/:name", handler(KindQuery))
+	de.Command("/user/:name", handler(KindCommand))
+	v1 := de.Group("/v1")
+	v1.Subscribe("/events", handler(KindSubscribe))
+	v1.HandleKind("DELETE", "/user/:name", handler("DELETE"))
+
+	tests := []struct {
+		kind string
+		path string
+		code int
+		body string
+	}{
+		{"", "/user/a", http.StatusOK, KindQuery},
+		{KindQuery, "/user/a", http.StatusOK, KindQuery},
+		{KindCommand, "/user/a", http.StatusOK, KindCommand},
+		{KindSubscribe, "/v1/events", http.StatusOK, KindSubscribe},
+		{"DELETE", "/v1/user/a", http.StatusOK, "DELETE"},
+		{KindSubscribe, "/user/a", http.StatusNotFound, ""},
+		{"UNKNOWN", "/user/a", http.StatusNotFound, ""},
+	}
+	for _, tt := range tests {
+		resp := de.ServeRequest(context.Background(), tt.path, server.Request{Kind: tt.kind})
+		assert.DeepEqual(t, tt.code, resp.StatusCode)
+		assert.DeepEqual(t, tt.body, string(resp.Body))
+	}
+
+	de.HandleKindNotAllowed = true
+	resp := de.ServeRequest(context.Background(), "/user/a", server.Request{Kind: KindSubscribe})
+	assert.DeepEqual(t, http.StatusMethodNotAllowed, resp.StatusCode)
+	assert.DeepEqual(t, []string{KindQuery, KindCommand}, resp.Allow)
+	assert.True(t, errors.Is(resp.Err, ErrKindNotAllowed))
+
+	de.NoKind(func(c context.Context, ctx *server.RequestContext) {
+		ctx.String(ctx.Response.StatusCode, "allow: %v", ctx.Response.Allow)
+	})
+	resp = de.ServeRequest(context.Background(), "/v1/events", server.Request{Kind: KindCommand})
+	assert.DeepEqual(t, http.StatusMethodNotAllowed, resp.StatusCode)
+	assert.DeepEqual(t, "allow: [SUBSCRIBE]", string(resp.Body))
+
+	// 其他操作类型也不存在时仍然是 404
+	resp = de.ServeRequest(context.Background(), "/missing", server.Request{Kind: KindCommand})
+	assert.DeepEqual(t, http.StatusNotFound, resp.StatusCode)
+	assert.Nil(t, resp.Allow)
+}
+
+func TestEngine_ConcurrentNoKind(t *testing.T) {
+	de := NewEngine()
+	de.HandleKindNotAllowed = true
+	de.Command("/user", HandlerTest1)
+
+	const n = 100
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < n; i++ {
+			if i%20 == 0 {
+				de.Use(func(c context.Context, ctx *server.RequestContext) {})
+			}
+			de.NoKind(func(c context.Context, ctx *server.RequestContext) {
+				ctx.String(ctx.Response.StatusCode, "not allowed")
+			})
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < n; i++ {
+			resp := de.ServePath(context.Background(), "/user", nil)
+			if resp.StatusCode != http.StatusMethodNotAllowed {
+				t.Errorf("unexpected status: %d", resp.StatusCode)
+			}
+		}
+	}()
+	wg.Wait()
+
+	resp := de.ServePath(context.Background(), "/user", nil)
+	assert.DeepEqual(t, "not allowed", string(resp.Body))
+}
+
+func TestEngine_RoutesInfo(t *testing.T) {
+	de := NewEngine()
+	de.Use(HandlerTest2)
+	de.Handle("/", HandlerTest1)
+	de.Query("/user/:name", HandlerTest2)
+	de.Command("/user/:name", HandlerTest1)
+	v1 := de.Group("/v1", HandlerTest2)
+	{
+		v1.Handle("/files/:dir/*filepath", HandlerTest1)
+		v1.Subscribe("/events", HandlerTest2)
+	}
+
+	routes := de.Routes()
+	assert.DeepEqual(t, 5, len(routes))
+	for i := range routes {
+		routes[i].HandlerFunc = nil
+	}
+	const pkg = "github.com/Yuki-J1/wailsrouter/pkg/route."
+	assert.DeepEqual(t, RouteInfo{KindQuery, "/", pkg + "HandlerTest1", nil, 2, nil}, routes[0])
+	assert.DeepEqual(t, RouteInfo{KindQuery, "/user/:name", pkg + "HandlerTest2", nil, 2, []string{"name"}}, routes[1])
+	assert.DeepEqual(t, RouteInfo{KindQuery, "/v1/files/:dir/*filepath", pkg + "HandlerTest1", nil, 3, []string{"dir", "filepath"}}, routes[2])
+	assert.DeepEqual(t, RouteInfo{KindCommand, "/user/:name", pkg + "HandlerTest1", nil, 2, []string{"name"}}, routes[3])
+	assert.DeepEqual(t, RouteInfo{KindSubscribe, "/v1/events", pkg + "HandlerTest2", nil, 3, nil}, routes[4])
+}
+
+func TestEngine_URLFor(t *testing.T) {
+	de := NewEngine()
+	de.Handle("/user/:name", HandlerTest1).Name("user")
+	v1 := de.Group("/v1")
+	route := v1.Command("/files/:dir/*filepath", HandlerTest1).Name("file")
+	assert.DeepEqual(t, KindCommand, route.Kind())
+	assert.DeepEqual(t, "/v1/files/:dir/*filepath", route.Path())
+	de.Handle("/user_:name/about", HandlerTest1).Name("about")
+
+	tests := []struct {
+		name   string
+		params []server.Param
+		path   string
+		err    error
+	}{
+		{"user", []server.Param{{Key: "name", Value: "YKJ"}}, "/user/YKJ", nil},
+		{"file", []server.Param{{Key: "filepath", Value: "a/b.txt"}, {Key: "dir", Value: "js"}}, "/v1/files/js/a/b.txt", nil},
+		{"file", []server.Param{{Key: "dir", Value: "js"}, {Key: "filepath", Value: ""}}, "/v1/files/js/", nil},
+		{"about", []server.Param{{Key: "name", Value: "gopher"}}, "/user_gopher/about", nil},
+		{"missing", nil, "", ErrRouteNameNotFound},
+		{"user", nil, "", ErrMissingParam},
+		{"user", []server.Param{{Key: "name", Value: ""}}, "", ErrMissingParam},
+		{"file", []server.Param{{Key: "dir", Value: "js"}}, "", ErrMissingParam},
+		{"user", []server.Param{{Key: "name", Value: "a/b"}}, "", ErrInvalidParam},
+		{"file", []server.Param{{Key: "dir", Value: "js"}, {Key: "filepath", Value: "a?b"}}, "/v1/files/js/a%3Fb", nil},
+		{"user", []server.Param{{Key: "name", Value: "50% off#1"}}, "/user/50%25%20off%231", nil},
+	}
+	for _, tt := range tests {
+		path, err := de.URLFor(tt.name, tt.params...)
+		assert.True(t, errors.Is(err, tt.err))
+		assert.DeepEqual(t, tt.path, path)
+	}
+
+	// 生成的 path 可以被正确分发
+	path, _ := de.URLFor("file", server.Param{Key: "dir", Value: "js"}, server.Param{Key: "filepath", Value: "a/b.txt"})
+	ctx := de.NewContext()
+	ctx.Path = []byte(path)
+	ctx.Request.Kind = KindCommand
+	de.Serve(context.Background(), ctx)
+	assert.DeepEqual(t, route.Path(), ctx.FullPath())
+
+	// 转义后的参数值在两种模式下都可以还原
+	de.Command("/echo/:name/*filepath", func(c context.Context, ctx *server.RequestContext) {
+		ctx.String(http.StatusOK, ctx.Params.ByName("name")+"|"+ctx.Params.ByName("filepath"))
+	}).Name("echo")
+	path, err := de.URLFor("echo", server.Param{Key: "name", Value: "50% off?#+"}, server.Param{Key: "filepath", Value: "a b/c?d#e%.txt"})
+	assert.Nil(t, err)
+	for _, useRawPath := range []bool{false, true} {
+		de.UseRawPath = useRawPath
+		resp := de.ServeRequest(context.Background(), path, server.Request{Kind: KindCommand})
+		assert.DeepEqual(t, "50% off?#+|a b/c?d#e%.txt", string(resp.Body))
+	}
+
+	// 名称重复
+	recv := catchPanic(func() {
+		de.Handle("/other", HandlerTest1).Name("user")
+	})
+	assert.True(t, recv != nil)
+}
+
+func TestEngine_TryHandle(t *testing.T) {
+	de := NewEngine()
+	_, err := de.TryHandle("/user/:name", HandlerTest1)
+	assert.Nil(t, err)
+
+	many := make([]server.HandlerFunc, 63)
+	for i := range many {
+		many[i] = HandlerTest2
+	}
+	tests := []struct {
+		path     string
+		handlers []server.HandlerFunc
+		err      error
+		existing string
+	}{
+		{"/user/:name", []server.HandlerFunc{HandlerTest2}, ErrDuplicateRoute, "/user/:name"},
+		{"/user/:/x", []server.HandlerFunc{HandlerTest2}, ErrInvalidWildcard, ""},
+		{"/src/*", []server.HandlerFunc{HandlerTest2}, ErrInvalidWildcard, ""},
+		{"/:foo:bar", []server.HandlerFunc{HandlerTest2}, ErrInvalidWildcard, ""},
+		{"/user/:n", []server.HandlerFunc{HandlerTest2}, ErrDuplicateRoute, "/user/:name"},
+		{"/many", many, ErrTooManyHandlers, ""},
+		{"/empty", nil, ErrNoHandler, ""},
+	}
+	for _, tt := range tests {
+		route, err := de.TryHandle(tt.path, tt.handlers...)
+		assert.Nil(t, route)
+		assert.True(t, errors.Is(err, tt.err))
+		var routeErr *RouteError
+		assert.True(t, errors.As(err, &routeErr))
+		assert.DeepEqual(t, tt.path, routeErr.Pattern)
+		assert.DeepEqual(t, tt.existing, routeErr.Existing)
+	}
+
+	// 不同操作类型下不冲突
+	_, err = de.TryHandleKind(KindCommand, "/user/:name", HandlerTest2)
+	assert.Nil(t, err)
+	_, err = de.TryHandleKind("", "/user/:name", HandlerTest2)
+	assert.True(t, errors.Is(err, ErrInvalidPath))
+
+	// 失败的注册不影响已有路由
+	resp := de.ServePath(context.Background(), "/user/a", nil)
+	assert.DeepEqual(t, http.StatusOK, resp.StatusCode)
+
+	v1, err := de.TryGroup("/v1", HandlerTest2)
+	assert.Nil(t, err)
+	_, err = v1.TryGroup("/v2", many...)
+	assert.True(t, errors.Is(err, ErrTooManyHandlers))
+	route, err := v1.TryHandle("/user/:name", HandlerTest1)
+	assert.Nil(t, err)
+	assert.DeepEqual(t, "/v1/user/:name", route.Path())
+}
+
+func TestEngine_ConcurrentRegister(t *testing.T) {
+	de := NewEngine()
+	de.Handle("/user/:name", func(c context.Context, ctx *server.RequestContext) {
+		ctx.String(http.StatusOK, ctx.Params.ByName("name"))
+	})
+
+	const n = 100
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < n; i++ {
+			// 分裂 /user/:name 所在的节点
+			de.Handle(fmt.Sprintf("/u%d/:id", i), HandlerTest2)
+			de.Command(fmt.Sprintf("/user/%d/x", i), HandlerTest2)
+			de.Handle(fmt.Sprintf("/user%d", i), HandlerTest2).Name(fmt.Sprintf("user%d", i))
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < n; i++ {
+			resp := de.ServePath(context.Background(), "/user/YKJ", nil)
+			if resp.StatusCode != http.StatusOK || string(resp.Body) != "YKJ" {
+				t.Errorf("unexpected response: %d %s", resp.StatusCode, resp.Body)
+			}
+			de.URLFor("user0")
+			de.Routes()
+		}
+	}()
+	wg.Wait()
+
+	assert.DeepEqual(t, 3*n+1, len(de.Routes()))
+	resp := de.ServeRequest(context.Background(), "/user/7/x", server.Request{Kind: KindCommand})
+	assert.DeepEqual(t, http.StatusOK, resp.StatusCode)
+}
+
+func BenchmarkEngine_Handle(b *testing.B) {
+	const n = 10000
+	paths := make([]string, n)
+	for i := range paths {
+		paths[i] = fmt.Sprintf("/api/v%d/user%d/:name", i%10, i)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		de := NewEngine()
+		for _, path := range paths {
+			de.Handle(path, HandlerTest1)
+		}
+	}
+}
+
+func TestEngine_RemoveAndReplace(t *testing.T) {
+	de := NewEngine()
+	de.RedirectTrailingSlash = false
+	var trace []string
+	de.Use(func(c context.Context, ctx *server.RequestContext) {
+		trace = append(trace, "middleware")
+	})
+	handler := func(body string) server.HandlerFunc {
+		return func(c context.Context, ctx *server.RequestContext) {
+			ctx.String(http.StatusOK, body)
+		}
+	}
+	de.Query("/user/:name", handler("query")).Name("user")
+	de.Command("/user/:name", handler("command"))
+	de.Handle("/user/:name/profile", handler("profile"))
+
+	assert.True(t, errors.Is(de.Replace("/missing", handler("x")), ErrRouteNotFound))
+	assert.True(t, errors.Is(de.Replace("/user/:name"), ErrNoHandler))
+	assert.Nil(t, de.Replace("/user/:name", handler("replaced")))
+	for _, kind := range []string{KindQuery, KindCommand} {
+		trace = nil
+		resp := de.ServeRequest(context.Background(), "/user/a", server.Request{Kind: kind})
+		assert.DeepEqual(t, "replaced", string(resp.Body))
+		assert.DeepEqual(t, []string{"middleware"}, trace)
+	}
+
+	assert.Nil(t, de.Remove("/user/:name"))
+	assert.True(t, errors.Is(de.Remove("/user/:name"), ErrRouteNotFound))
+	for _, kind := range []string{KindQuery, KindCommand} {
+		resp := de.ServeRequest(context.Background(), "/user/a", server.Request{Kind: kind})
+		assert.DeepEqual(t, http.StatusNotFound, resp.StatusCode)
+	}
+	resp := de.ServePath(context.Background(), "/user/a/profile", nil)
+	assert.DeepEqual(t, "profile", string(resp.Body))
+	assert.DeepEqual(t, 1, len(de.Routes()))
+
+	// 名称随路由一起删除
+	_, err := de.URLFor("user", server.Param{Key: "name", Value: "a"})
+	assert.True(t, errors.Is(err, ErrRouteNameNotFound))
+
+	// 删除后可以重新注册
+	de.Handle("/user/:name", handler("again")).Name("user")
+	resp = de.ServePath(context.Background(), "/user/a", nil)
+	assert.DeepEqual(t, "again", string(resp.Body))
+
+	// 路由组的中间件在替换后保留
+	admin := de.Group("/admin", func(c context.Context, ctx *server.RequestContext) {
+		if ctx.Request.Meta["token"] != "secret" {
+			ctx.AbortWithStatus(http.StatusUnauthorized)
+		}
+	})
+	admin.Handle("/users", handler("users"))
+	// 分裂 /admin/users 节点
+	admin.Handle("/u", handler("u"))
+	assert.Nil(t, de.Replace("/admin/users", handler("replaced users")))
+	trace = nil
+	resp = de.ServePath(context.Background(), "/admin/users", nil)
+	assert.DeepEqual(t, http.StatusUnauthorized, resp.StatusCode)
+	assert.DeepEqual(t, "", string(resp.Body))
+	assert.DeepEqual(t, []string{"middleware"}, trace)
+	resp = de.ServeRequest(context.Background(), "/admin/users", server.Request{Meta: map[string]string{"token": "secret"}})
+	assert.DeepEqual(t, "replaced users", string(resp.Body))
+	for _, r := range de.Routes() {
+		if r.Path == "/admin/users" {
+			assert.DeepEqual(t, 3, r.HandlerCount)
+		}
+	}
+
+	// 任意一种操作类型替换失败时 所有操作类型都不替换
+	// 先替换 A 再替换 B，B 的中间件过多
+	de.HandleKind("A", "/many", handler("a"))
+	many := de.Group("/", make(server.HandlersChain, 50)...)
+	many.HandleKind("B", "/many", handler("b"))
+	err = de.Replace("/many", make(server.HandlersChain, 12)...)
+	assert.True(t, errors.Is(err, ErrTooManyHandlers))
+	resp = de.ServeRequest(context.Background(), "/many", server.Request{Kind: "A"})
+	assert.DeepEqual(t, "a", string(resp.Body))
+}
+
+func TestEngine_Mount(t *testing.T) {
+	child := NewEngine()
+	var childFullPath string
+	child.Use(func(c context.Context, ctx *server.RequestContext) {
+		childFullPath = ctx.FullPath()
+	})
+	child.Handle("/", func(c context.Context, ctx *server.RequestContext) {
+		ctx.String(http.StatusOK, "index %s", ctx.Path)
+	})
+	child.Handle("/user/:name", func(c context.Context, ctx *server.RequestContext) {
+		ctx.String(http.StatusOK, "%s %s %s", ctx.Path, ctx.FullPath(), ctx.Params.ByName("name"))
+	})
+	child.HandleKind("DELETE", "/user/:name", func(c context.Context, ctx *server.RequestContext) {
+		ctx.String(http.StatusOK, "deleted %s", ctx.Params.ByName("name"))
+	})
+	child.Handle("/guard", func(c context.Context, ctx *server.RequestContext) {
+		ctx.AbortWithStatus(http.StatusForbidden)
+	})
+
+	de := NewEngine()
+	var trace []string
+	admin := de.Group("/admin/:tenant", func(c context.Context, ctx *server.RequestContext) {
+		trace = append(trace, "parent "+ctx.Params.ByName("tenant"))
+		ctx.Next(c)
+		trace = append(trace, fmt.Sprintf("parent done %s %s %t", ctx.FullPath(), ctx.Path, ctx.IsAborted()))
+	})
+	admin.Mount("/m", child)
+
+	tests := []struct {
+		kind      string
+		path      string
+		code      int
+		body      string
+		fullPath  string
+		childPath string
+		aborted   bool
+	}{
+		{"", "/admin/t1/m/user/YKJ", http.StatusOK, "/user/YKJ /user/:name YKJ", "/admin/:tenant/m/*mountpath", "/user/:name", false},
+		{"", "/admin/t1/m", http.StatusOK, "index /", "/admin/:tenant/m", "/", false},
+		{"", "/admin/t1/m/", http.StatusOK, "index /", "/admin/:tenant/m/*mountpath", "/", false},
+		{"DELETE", "/admin/t1/m/user/YKJ", http.StatusOK, "deleted YKJ", "/admin/:tenant/m/*mountpath", "/user/:name", false},
+		{"", "/admin/t1/m/guard", http.StatusForbidden, "", "/admin/:tenant/m/*mountpath", "/guard", true},
+		{"", "/admin/t1/m/missing", http.StatusNotFound, "", "/admin/:tenant/m/*mountpath", "", false},
+	}
+	for _, tt := range tests {
+		trace, childFullPath = nil, ""
+		ctx := de.NewContext()
+		ctx.Path = []byte(tt.path)
+		ctx.Request.Kind = tt.kind
+		de.Serve(context.Background(), ctx)
+		assert.DeepEqual(t, tt.code, ctx.Response.StatusCode)
+		assert.DeepEqual(t, tt.body, string(ctx.Response.Body))
+		assert.DeepEqual(t, tt.childPath, childFullPath)
+		assert.DeepEqual(t, []string{"parent t1", fmt.Sprintf("parent done %s %s %t", tt.fullPath, tt.path, tt.aborted)}, trace)
+		// 恢复父路由的参数
+		assert.DeepEqual(t, "t1", ctx.Params.ByName("tenant"))
+	}
+
+	// 本地路径保持原始编码，参数值只在 child 中解码一次
+	for _, tt := range []struct {
+		useRawPath bool
+		path       string
+	}{
+		{false, "/admin/t1/m/user/%2541"},
+		{false, "/admin/%E4%BD%A0/m/user/%2541"},
+		{true, "/admin/t1/m/user/%2541"},
+		{true, "/admin/t%2F1/m/user/%2541"},
+	} {
+		de.UseRawPath = tt.useRawPath
+		resp := de.ServePath(context.Background(), tt.path, nil)
+		assert.DeepEqual(t, "/user/%2541 /user/:name %41", string(resp.Body))
+	}
+
+	// child 重定向的规范路径加上挂载的前缀
+	child.RedirectFixedPath = true
+	for _, tt := range []struct {
+		useRawPath bool
+		path       string
+		location   string
+	}{
+		{false, "/admin/t1/m/user/42/", "/admin/t1/m/user/42"},
+		{false, "/admin/t1/m/USER/42?x=1", "/admin/t1/m/user/42?x=1"},
+		{false, "/admin/%E4%BD%A0/m/user/%2541/", "/admin/%E4%BD%A0/m/user/%2541"},
+		{true, "/admin/t%2F1/m/user/42/", "/admin/t%2F1/m/user/42"},
+	} {
+		de.UseRawPath = tt.useRawPath
+		resp := de.ServePath(context.Background(), tt.path, nil)
+		assert.DeepEqual(t, http.StatusMovedPermanently, resp.StatusCode)
+		assert.DeepEqual(t, tt.location, resp.Location)
+		resp = de.ServePath(context.Background(), resp.Location, nil)
+		assert.DeepEqual(t, http.StatusOK, resp.StatusCode)
+	}
+}
+
+func TestEngine_ParamConstraint(t *testing.T) {
+	de := NewEngine()
+	de.Handle("/user/:id<int>", func(c context.Context, ctx *server.RequestContext) {
+		ctx.String(http.StatusOK, "id %s", ctx.Params.ByName("id"))
+	}).Name("user")
+	de.Handle("/user/*rest", func(c context.Context, ctx *server.RequestContext) {
+		ctx.String(http.StatusOK, "rest %s", ctx.Params.ByName("rest"))
+	})
+
+	resp := de.ServePath(context.Background(), "/user/42", nil)
+	assert.DeepEqual(t, "id 42", string(resp.Body))
+	// 不满足约束时回溯到 * 节点
+	resp = de.ServePath(context.Background(), "/user/abc", nil)
+	assert.DeepEqual(t, "rest abc", string(resp.Body))
+
+	path, err := de.URLFor("user", server.Param{Key: "id", Value: "7"})
+	assert.Nil(t, err)
+	assert.DeepEqual(t, "/user/7", path)
+	_, err = de.URLFor("user", server.Param{Key: "id", Value: "abc"})
+	assert.True(t, errors.Is(err, ErrInvalidParam))
+
+	_, err = de.TryHandle("/user/:id<nope>/posts", HandlerTest2)
+	assert.True(t, errors.Is(err, ErrInvalidWildcard))
+
+	// 同一位置可以注册约束不同的参数，依次尝试后才回溯到 * 节点
+	de.Handle("/user/:name<alpha>", func(c context.Context, ctx *server.RequestContext) {
+		ctx.String(http.StatusOK, "name %s", ctx.Params.ByName("name"))
+	})
+	de.Handle("/user/:uuid<uuid>/posts", func(c context.Context, ctx *server.RequestContext) {
+		ctx.String(http.StatusOK, "posts %s", ctx.Params.ByName("uuid"))
+	})
+	for path, body := range map[string]string{
+		"/user/42":  "id 42",
+		"/user/abc": "name abc",
+		"/user/a1":  "rest a1",
+		"/user/123e4567-e89b-12d3-a456-426614174000/posts": "posts 123e4567-e89b-12d3-a456-426614174000",
+		"/user/42/posts": "rest 42/posts",
+	} {
+		resp = de.ServePath(context.Background(), path, nil)
+		assert.DeepEqual(t, body, string(resp.Body))
+	}
+}
+
+func TestEngine_OptionalParam(t *testing.T) {
+	de := NewEngine()
+	de.Handle("/user/:name?", func(c context.Context, ctx *server.RequestContext) {
+		name, ok := ctx.Params.Get("name")
+		ctx.String(http.StatusOK, "%s %s %t", ctx.FullPath(), name, ok)
+	}).Name("user")
+
+	resp := de.ServePath(context.Background(), "/user/YKJ", nil)
+	assert.DeepEqual(t, "/user/:name? YKJ true", string(resp.Body))
+	resp = de.ServePath(context.Background(), "/user", nil)
+	assert.DeepEqual(t, "/user/:name?  false", string(resp.Body))
+
+	// 两种形式只作为一个路由出现
+	routes := de.Routes()
+	assert.DeepEqual(t, 1, len(routes))
+	assert.DeepEqual(t, "/user/:name?", routes[0].Path)
+	assert.DeepEqual(t, []string{"name"}, routes[0].ParamNames)
+
+	path, err := de.URLFor("user", server.Param{Key: "name", Value: "YKJ"})
+	assert.Nil(t, err)
+	assert.DeepEqual(t, "/user/YKJ", path)
+	path, err = de.URLFor("user")
+	assert.Nil(t, err)
+	assert.DeepEqual(t, "/user", path)
+
+	assert.Nil(t, de.Replace("/user/:name?", func(c context.Context, ctx *server.RequestContext) {
+		ctx.String(http.StatusOK, "replaced")
+	}))
+	resp = de.ServePath(context.Background(), "/user", nil)
+	assert.DeepEqual(t, "replaced", string(resp.Body))
+
+	assert.Nil(t, de.Remove("/user/:name?"))
+	resp = de.ServePath(context.Background(), "/user/YKJ", nil)
+	assert.DeepEqual(t, http.StatusNotFound, resp.StatusCode)
+	resp = de.ServePath(context.Background(), "/user", nil)
+	assert.DeepEqual(t, http.StatusNotFound, resp.StatusCode)
+}
+
+func TestEngine_MultiParamSegment(t *testing.T) {
+	de := NewEngine()
+	de.Handle("/files/:name.:ext", func(c context.Context, ctx *server.RequestContext) {
+		ctx.String(http.StatusOK, "%s %s", ctx.Params.ByName("name"), ctx.Params.ByName("ext"))
+	}).Name("file")
+
+	resp := de.ServePath(context.Background(), "/files/report.pdf", nil)
+	assert.DeepEqual(t, "report pdf", string(resp.Body))
+
+	path, err := de.URLFor("file", server.Param{Key: "name", Value: "report"}, server.Param{Key: "ext", Value: "tar.gz"})
+	assert.Nil(t, err)
+	assert.DeepEqual(t, "/files/report.tar.gz", path)
+	// 参数值包含分隔符时无法被正确分发
+	_, err = de.URLFor("file", server.Param{Key: "name", Value: "a.b"}, server.Param{Key: "ext", Value: "txt"})
+	assert.True(t, errors.Is(err, ErrInvalidParam))
+}
+
+func TestEngine_Query(t *testing.T) {
+	de := NewEngine()
+	de.Handle("/search", func(c context.Context, ctx *server.RequestContext) {
+		ctx.String(http.StatusOK, "%s %s %s %s %v", ctx.Path, ctx.FullPath(), ctx.Query("q"), ctx.DefaultQuery("page", "1"), ctx.QueryArray("tag"))
+	})
+	child := NewEngine()
+	child.Handle("/list", func(c context.Context, ctx *server.RequestContext) {
+		ctx.String(http.StatusOK, "%s %s", ctx.Path, ctx.Query("q"))
+	})
+	de.Mount("/child", child)
+
+	tests := []struct {
+		path     string
+		body     string
+		location string
+	}{
+		{"/search?q=go+lang&page=2&tag=a&tag=b", "/search /search go lang 2 [a b]", ""},
+		{"/search", "/search /search  1 []", ""},
+		{"/search?q=%E4%BD%A0", "/search /search 你 1 []", ""},
+		{"/search/?q=go", "", "/search?q=go"},
+		{"/child/list?q=go", "/list go", ""},
+	}
+	for _, tt := range tests {
+		resp := de.ServePath(context.Background(), tt.path, nil)
+		assert.DeepEqual(t, tt.body, string(resp.Body))
+		assert.DeepEqual(t, tt.location, resp.Location)
+	}
+
+	// 向 Path 追加内容不会影响查询参数
+	de.Handle("/append", func(c context.Context, ctx *server.RequestContext) {
+		ctx.Path = append(ctx.Path, "/more"...)
+		ctx.String(http.StatusOK, "%s %s", ctx.Path, ctx.Query("q"))
+	})
+	resp := de.ServePath(context.Background(), "/append?q=go", nil)
+	assert.DeepEqual(t, "/append/more go", string(resp.Body))
+
+	// 去掉查询字符串后 Path 的容量不变
+	ctx := de.NewContext()
+	ctx.Path = append(make([]byte, 0, 64), "/search?q=go"...)
+	de.Serve(context.Background(), ctx)
+	assert.DeepEqual(t, "/search", string(ctx.Path))
+	assert.DeepEqual(t, 64, cap(ctx.Path))
+}
+
+func TestEngine_Codecs(t *testing.T) {
+	type user struct {
+		ID   int64  `path:"id" json:"id"`
+		Name string `json:"name"`
+	}
+	de := NewEngine()
+	de.Codecs.Register(msgpack.Codec{})
+	de.Codecs.Register(cbor.Codec{})
+	de.Handle("/user/:id", func(c context.Context, ctx *server.RequestContext) {
+		var u user
+		if err := ctx.Bind(&u); err != nil {
+			ctx.AbortWithError(http.StatusBadRequest, err)
+			return
+		}
+		ctx.Render(http.StatusOK, u)
+	})
+
+	for _, c := range []codec.Codec{codec.JSON{}, msgpack.Codec{}, cbor.Codec{}} {
+		body, err := c.Marshal(user{Name: "YKJ"})
+		assert.Nil(t, err)
+		resp := de.ServeRequest(context.Background(), "/user/42", server.Request{Body: body, ContentType: c.ContentType()})
+		assert.DeepEqual(t, http.StatusOK, resp.StatusCode)
+		// 没有 Accept 时使用请求体的编码类型
+		assert.DeepEqual(t, c.ContentType(), resp.ContentType)
+		var u user
+		assert.Nil(t, c.Unmarshal(resp.Body, &u))
+		assert.DeepEqual(t, user{ID: 42, Name: "YKJ"}, u)
+	}
+
+	// Accept 优先
+	req := server.Request{Body: []byte(`{"name":"YKJ"}`), ContentType: server.MIMEApplicationJSON}
+	req.SetMeta(server.MetaAccept, "cbor")
+	resp := de.ServeRequest(context.Background(), "/user/42", req)
+	assert.DeepEqual(t, "application/cbor", resp.ContentType)
+
+	// 没有注册的编码类型
+	resp = de.ServeRequest(context.Background(), "/user/42", server.Request{Body: []byte("x"), ContentType: "application/xml"})
+	assert.DeepEqual(t, http.StatusBadRequest, resp.StatusCode)
+	assert.True(t, errors.Is(resp.Err, server.ErrBindContentType))
+}
+
+type typedUserReq struct {
+	ID     int64  `path:"id"`
+	Prefix string `query:"prefix" default:"user"`
+}
+
+type typedStatusError struct {
+	code int
+	msg  string
+}
+
+func (e typedStatusError) Error() string   { return e.msg }
+func (e typedStatusError) StatusCode() int { return e.code }
+
+func TestTyped(t *testing.T) {
+	de := NewEngine()
+	var trace []string
+	auth := func(c context.Context, ctx *server.RequestContext) {
+		trace = append(trace, "auth")
+		ctx.Next(c)
+		trace = append(trace, fmt.Sprintf("auth done %d %t", ctx.Response.StatusCode, ctx.IsAborted()))
+	}
+	de.Handle("/user/:id", auth, Typed(func(c context.Context, req typedUserReq) (map[string]string, error) {
+		switch req.ID {
+		case 0:
+			return nil, errors.New("no user")
+		case 1:
+			return nil, typedStatusError{http.StatusForbidden, "forbidden user"}
+		case 2:
+			return nil, fmt.Errorf("load user: %w", typedStatusError{http.StatusNotFound, "no such user"})
+		case 3:
+			return nil, typedStatusError{http.StatusOK, "not an error status"}
+		}
+		return map[string]string{"name": fmt.Sprintf("%s-%d", req.Prefix, req.ID)}, nil
+	}))
+	de.Command("/echo", Typed(func(c context.Context, req []string) (int, error) {
+		return len(req), nil
+	}))
+	de.Command("/ptr/:id", Typed(func(c context.Context, req *typedUserReq) (*typedUserReq, error) {
+		return req, nil
+	}))
+
+	tests := []struct {
+		kind    string
+		path    string
+		payload string
+		code    int
+		body    string
+		trace   []string
+	}{
+		{"", "/user/42?prefix=u", "", http.StatusOK, `{"name":"u-42"}`, []string{"auth", "auth done 200 false"}},
+		{"", "/user/abc", "", http.StatusBadRequest, "", []string{"auth", "auth done 400 true"}},
+		{"", "/user/0", "", http.StatusInternalServerError, `{"code":"internal","message":"no user"}`, []string{"auth", "auth done 500 true"}},
+		{"", "/user/1", "", http.StatusForbidden, `{"code":"forbidden","message":"forbidden user"}`, []string{"auth", "auth done 403 true"}},
+		// 包装后的错误同样可以指定状态码
+		{"", "/user/2", "", http.StatusNotFound, `{"code":"not_found","message":"load user: no such user"}`, []string{"auth", "auth done 404 true"}},
+		// 不是错误状态码时忽略
+		{"", "/user/3", "", http.StatusInternalServerError, `{"code":"internal","message":"not an error status"}`, []string{"auth", "auth done 500 true"}},
+		{KindCommand, "/echo", `["a","b"]`, http.StatusOK, "2", nil},
+		{KindCommand, "/echo", `{`, http.StatusBadRequest, "", nil},
+		{KindCommand, "/ptr/7", "", http.StatusOK, `{"ID":7,"Prefix":"user"}`, nil},
+	}
+	for _, tt := range tests {
+		trace = nil
+		resp := de.ServeRequest(context.Background(), tt.path, server.Request{Kind: tt.kind, Body: []byte(tt.payload)})
+		assert.DeepEqual(t, tt.code, resp.StatusCode)
+		assert.DeepEqual(t, tt.trace, trace)
+		if tt.body != "" {
+			assert.DeepEqual(t, tt.body, string(resp.Body))
+		}
+		assert.DeepEqual(t, tt.code != http.StatusOK, resp.Err != nil)
+	}
+}
+
+func TestEngine_ErrorHandler(t *testing.T) {
+	de := NewEngine()
+	de.HandleKindNotAllowed = true
+	de.ErrorHandler = DefaultErrorHandler
+	de.PanicHandler = func(c context.Context, ctx *server.RequestContext) {}
+	de.Handle("/user/:id", func(c context.Context, ctx *server.RequestContext) {
+		id, ok := ctx.MustParamInt("id")
+		if !ok {
+			return
+		}
+		ctx.String(http.StatusOK, "%d", id)
+	})
+	de.Command("/user/:id", HandlerTest2)
+	de.Handle("/panic", func(c context.Context, ctx *server.RequestContext) {
+		panic("boom")
+	})
+	de.Handle("/conflict", func(c context.Context, ctx *server.RequestContext) {
+		ctx.Error(errors.New("first"))
+		ctx.AbortWithError(http.StatusConflict, &Error{Code: "name_taken", Message: "name is already taken", Details: "YKJ"})
+	})
+	de.Handle("/soft", func(c context.Context, ctx *server.RequestContext) {
+		ctx.Error(errors.New("soft"))
+	})
+
+	tests := []struct {
+		kind string
+		path string
+		want ErrorEnvelope
+	}{
+		{"", "/user/42", ErrorEnvelope{}},
+		{"", "/missing", ErrorEnvelope{Status: http.StatusNotFound, Path: "/missing", Error: &Error{Code: CodeNotFound, Message: ErrNoRoute.Error()}}},
+		{"DELETE", "/user/42", ErrorEnvelope{Status: http.StatusMethodNotAllowed, Path: "/user/42", Error: &Error{Code: CodeKindNotAllowed, Message: ErrKindNotAllowed.Error(), Details: []interface{}{KindQuery, KindCommand}}}},
+		{"", "/user/abc", ErrorEnvelope{Status: http.StatusBadRequest, Path: "/user/abc", Route: "/user/:id", Error: &Error{Code: CodeBadParam, Message: "param 'id' of route '/user/:id': invalid int value 'abc': invalid syntax", Details: []interface{}{
+			map[string]interface{}{"source": "path", "name": "id", "value": "abc", "reason": "invalid syntax"},
+		}}}},
+		{"", "/panic", ErrorEnvelope{Status: http.StatusInternalServerError, Path: "/panic", Route: "/panic", Error: &Error{Code: CodePanic, Message: "boom"}}},
+		{"", "/conflict", ErrorEnvelope{Status: http.StatusConflict, Path: "/conflict", Route: "/conflict", Error: &Error{Code: "name_taken", Message: "name is already taken", Details: "YKJ"}}},
+	}
+	for _, tt := range tests {
+		resp := de.ServeRequest(context.Background(), tt.path, server.Request{Kind: tt.kind})
+		if tt.want.Error == nil {
+			assert.DeepEqual(t, "42", string(resp.Body))
+			continue
+		}
+		var got ErrorEnvelope
+		assert.Nil(t, json.Unmarshal(resp.Body, &got))
+		assert.DeepEqual(t, tt.want.Status, resp.StatusCode)
+		assert.DeepEqual(t, tt.want.Status, got.Status)
+		assert.DeepEqual(t, tt.want.Path, got.Path)
+		assert.DeepEqual(t, tt.want.Route, got.Route)
+		assert.DeepEqual(t, tt.want.Error, got.Error)
+		assert.DeepEqual(t, got.Error, got.Errors[len(got.Errors)-1])
+	}
+
+	// 所有错误都会被记录
+	ctx := de.NewContext()
+	ctx.Path = []byte("/conflict")
+	de.Serve(context.Background(), ctx)
+	assert.DeepEqual(t, []string{"first", "name is already taken"}, ctx.Errors.Strings())
+	var e *Error
+	assert.True(t, errors.As(ctx.Errors.Last(), &e))
+	assert.DeepEqual(t, "name_taken", e.Code)
+
+	// 没有设置错误状态码时 响应保持原样
+	ctx = de.NewContext()
+	ctx.Path = []byte("/soft")
+	de.Serve(context.Background(), ctx)
+	assert.DeepEqual(t, http.StatusOK, ctx.Response.StatusCode)
+	assert.DeepEqual(t, "", string(ctx.Response.Body))
+	assert.DeepEqual(t, []string{"soft"}, ctx.Errors.Strings())
+
+	// 添加允许的操作类型时不修改 handler 记录的 *Error
+	own := &Error{Code: CodeKindNotAllowed, Message: "not allowed", Cause: ErrKindNotAllowed}
+	de.Handle("/own", func(c context.Context, ctx *server.RequestContext) {
+		ctx.Response.Allow = []string{KindCommand}
+		ctx.AbortWithError(http.StatusMethodNotAllowed, own)
+	})
+	resp := de.ServePath(context.Background(), "/own", nil)
+	assert.True(t, bytes.Contains(resp.Body, []byte(`"details":["COMMAND"]`)))
+	assert.Nil(t, own.Details)
+}
+
+func TestEngine_ErrorHandlerOnce(t *testing.T) {
+	var calls []string
+	errorHandler := func(name string) server.HandlerFunc {
+		return func(c context.Context, ctx *server.RequestContext) {
+			calls = append(calls, name)
+			DefaultErrorHandler(c, ctx)
+		}
+	}
+	child := NewEngine()
+	child.ErrorHandler = errorHandler("child")
+	child.Handle("/bad", func(c context.Context, ctx *server.RequestContext) {
+		ctx.AbortWithError(http.StatusBadRequest, errors.New("bad"))
+	})
+	de := NewEngine()
+	de.ErrorHandler = errorHandler("parent")
+	de.NoRoute(func(c context.Context, ctx *server.RequestContext) {
+		ctx.String(http.StatusNotFound, "custom not found")
+	})
+	de.Handle("/typed", Typed(func(c context.Context, req struct{}) (string, error) {
+		return "", errors.New("typed")
+	}))
+	de.Mount("/child", child)
+
+	// 挂载的 child 不执行自己的 ErrorHandler
+	resp := de.ServePath(context.Background(), "/child/bad", nil)
+	assert.DeepEqual(t, http.StatusBadRequest, resp.StatusCode)
+	assert.DeepEqual(t, []string{"parent"}, calls)
+
+	// 已经写入的响应不会被覆盖
+	calls = nil
+	resp = de.ServePath(context.Background(), "/missing", nil)
+	assert.DeepEqual(t, "custom not found", string(resp.Body))
+	assert.DeepEqual(t, 0, len(calls))
+
+	// Typed 失败时只记录错误，由 ErrorHandler 渲染响应体
+	resp = de.ServePath(context.Background(), "/typed", nil)
+	assert.DeepEqual(t, http.StatusInternalServerError, resp.StatusCode)
+	assert.DeepEqual(t, `{"status":500,"path":"/typed","route":"/typed","error":{"code":"internal","message":"typed"},"errors":[{"code":"internal","message":"typed"}]}`, string(resp.Body))
+	assert.DeepEqual(t, []string{"parent"}, calls)
+	assert.DeepEqual(t, "typed", resp.Err.Error())
+}
+
+func TestRecovery(t *testing.T) {
+	errBoom := errors.New("boom")
+	var (
+		gotErr   interface{}
+		gotStack []byte
+	)
+	de := NewEngine()
+	de.Use(Recovery(WithRecoveryHandler(func(c context.Context, ctx *server.RequestContext, err interface{}, stack []byte) {
+		gotErr, gotStack = err, stack
+		ctx.String(http.StatusInternalServerError, "recovered")
+	})))
+	de.Handle("/panic/:id", func(c context.Context, ctx *server.RequestContext) {
+		panic(errBoom)
+	}, HandlerTest2)
+
+	ctx := de.NewContext()
+	ctx.Path = []byte("/panic/42")
+	de.Serve(context.Background(), ctx)
+	assert.DeepEqual(t, http.StatusInternalServerError, ctx.Response.StatusCode)
+	assert.DeepEqual(t, "recovered", string(ctx.Response.Body))
+	assert.True(t, ctx.IsAborted())
+	assert.DeepEqual(t, errBoom, gotErr)
+	assert.True(t, bytes.Contains(gotStack, []byte("TestRecovery")))
+
+	// 记录的错误可以获取 panic 的值和调用栈
+	var e *Error
+	assert.True(t, errors.As(ctx.Errors.Last(), &e))
+	assert.DeepEqual(t, CodePanic, e.Code)
+	assert.DeepEqual(t, "boom", e.Error())
+	assert.True(t, errors.Is(ctx.Errors.Last(), errBoom))
+	var pe *PanicError
+	assert.True(t, errors.As(ctx.Errors.Last(), &pe))
+	assert.DeepEqual(t, "/panic/:id", pe.Route)
+	assert.DeepEqual(t, gotStack, pe.Stack)
+
+	// 重新 panic
+	de = NewEngine()
+	de.Use(Recovery(WithRepanic()))
+	de.Handle("/panic", func(c context.Context, ctx *server.RequestContext) {
+		panic("boom")
+	})
+	ctx = de.NewContext()
+	ctx.Path = []byte("/panic")
+	recv := catchPanic(func() {
+		de.Serve(context.Background(), ctx)
+	})
+	assert.DeepEqual(t, "boom", recv)
+	assert.DeepEqual(t, http.StatusInternalServerError, ctx.Response.StatusCode)
+	assert.DeepEqual(t, []string{"boom"}, ctx.Errors.Strings())
+}
+
+func TestEngine_PanicHandler(t *testing.T) {
+	type ctxKey struct{}
+	de := NewEngine()
+	de.PanicHandler = func(c context.Context, ctx *server.RequestContext) {
+		var pe *PanicError
+		if errors.As(ctx.Errors.Last(), &pe) {
+			ctx.String(ctx.Response.StatusCode, "%v %v %s", c.Value(ctxKey{}), pe.Value, pe.Route)
+		}
+	}
+	de.Handle("/panic/:id", func(c context.Context, ctx *server.RequestContext) {
+		panic("boom")
+	})
+
+	c := context.WithValue(context.Background(), ctxKey{}, "trace")
+	resp := de.ServeRequest(c, "/panic/42", server.Request{})
+	assert.DeepEqual(t, http.StatusInternalServerError, resp.StatusCode)
+	assert.DeepEqual(t, "trace boom /panic/:id", string(resp.Body))
+
+	// ErrorHandler 中的 panic 只交给 PanicHandler 处理
+	calls := 0
+	de = NewEngine()
+	de.PanicHandler = func(c context.Context, ctx *server.RequestContext) {
+		calls++
+	}
+	de.ErrorHandler = func(c context.Context, ctx *server.RequestContext) {
+		panic("error handler")
+	}
+	resp = de.ServePath(context.Background(), "/missing", nil)
+	assert.DeepEqual(t, 1, calls)
+	assert.DeepEqual(t, http.StatusInternalServerError, resp.StatusCode)
+
+	// 没有 PanicHandler 时 Engine 不捕获 panic
+	de = NewEngine()
+	de.Handle("/panic", func(c context.Context, ctx *server.RequestContext) {
+		panic("boom")
+	})
+	recv := catchPanic(func() {
+		de.ServePath(context.Background(), "/panic", nil)
+	})
+	assert.DeepEqual(t, "boom", recv)
+}
+
 func HandlerTest1(c context.Context, ctx *server.RequestContext) {
 	fmt.Print("handlerTest1")
 }
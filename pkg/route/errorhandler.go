@@ -0,0 +1,60 @@
+package route
+
+import (
+	"context"
+	"errors"
+	"github.com/Yuki-J1/wailsrouter/pkg/app/server"
+)
+
+// ErrorEnvelope DefaultErrorHandler 写入响应体的内容
+//
+//	{"status":404,"path":"/missing","error":{"code":"not_found","message":"route: no route matched"},"errors":[...]}
+type ErrorEnvelope struct {
+	Status int      `json:"status"`          // 状态码
+	Path   string   `json:"path"`            // 请求的 path
+	Route  string   `json:"route,omitempty"` // 匹配到的路由规则，没有匹配到路由时为空
+	Error  *Error   `json:"error"`           // 最后记录的错误，通常是导致请求失败的错误
+	Errors []*Error `json:"errors"`          // 所有记录的错误，按记录顺序排列
+}
+
+// DefaultErrorHandler 将 ctx.Errors 转换为 *Error 后以 ErrorEnvelope 写入响应体，编码方式与 ctx.Render 相同
+// 响应的状态码不是错误状态码时不做任何处理，只记录了错误的成功响应保持原样，错误仍然保留在 ctx.Errors 中
+//
+//	engine.ErrorHandler = route.DefaultErrorHandler
+func DefaultErrorHandler(c context.Context, ctx *server.RequestContext) {
+	status := ctx.Response.StatusCode
+	if status < 400 {
+		return
+	}
+	errs := make([]*Error, len(ctx.Errors))
+	for i, err := range ctx.Errors {
+		errs[i] = AsError(err, status)
+		// 允许的操作类型，复制后再修改，不改动 handler 记录的 *Error
+		if errors.Is(err, ErrKindNotAllowed) && errs[i].Details == nil {
+			e := *errs[i]
+			e.Details = ctx.Response.Allow
+			errs[i] = &e
+		}
+	}
+	ctx.Render(status, ErrorEnvelope{
+		Status: status,
+		Path:   string(ctx.Path),
+		Route:  ctx.FullPath(),
+		Error:  errs[len(errs)-1],
+		Errors: errs,
+	})
+}
+
+// handleErrors 请求处理完成后存在错误，并且 handler 没有写入响应体时，执行 ErrorHandler
+// ErrorHandler 为 nil 时只写入 Typed 记录的错误
+// 只由最外层的 Serve 执行，挂载的 child 不会执行自己的 ErrorHandler
+func (engine *Engine) handleErrors(c context.Context, ctx *server.RequestContext) {
+	if len(ctx.Errors) == 0 || ctx.Response.Written() {
+		return
+	}
+	if engine.ErrorHandler == nil {
+		renderTyped(ctx)
+		return
+	}
+	engine.ErrorHandler(c, ctx)
+}
@@ -0,0 +1,156 @@
+package route
+
+import (
+	"errors"
+	"github.com/Yuki-J1/wailsrouter/pkg/app/server"
+	"net/http"
+)
+
+var (
+	// ErrNoRoute 请求的 path 没有匹配到任何路由
+	ErrNoRoute = errors.New("route: no route matched")
+	// ErrKindNotAllowed 请求的 path 只在其他操作类型下注册了路由
+	ErrKindNotAllowed = errors.New("route: kind not allowed")
+
+	// ErrRouteNotFound Remove 或 Replace 的路由规则没有注册
+	ErrRouteNotFound = errors.New("route: route not found")
+
+	// ErrRouteNameNotFound URLFor 的路由名称不存在
+	ErrRouteNameNotFound = errors.New("route: route name not found")
+	// ErrMissingParam URLFor 缺少路由规则需要的参数
+	ErrMissingParam = errors.New("route: missing param")
+	// ErrInvalidParam URLFor 的参数值包含非法字符
+	ErrInvalidParam = errors.New("route: invalid param")
+)
+
+// 注册路由失败的原因，可以通过 errors.Is 判断 *RouteError
+var (
+	// ErrInvalidPath 路由规则或操作类型为空、不以 / 开头
+	ErrInvalidPath = errors.New("route: invalid path")
+	// ErrInvalidWildcard 路由规则中的 : 或 * 不合法
+	ErrInvalidWildcard = errors.New("route: invalid wildcard")
+	// ErrDuplicateRoute 路由规则已经注册过 handler
+	ErrDuplicateRoute = errors.New("route: duplicate route")
+	// ErrTooManyHandlers 调用链中的 handler 超过 63 个
+	ErrTooManyHandlers = errors.New("route: too many handlers")
+	// ErrNoHandler 路由规则没有 handler
+	ErrNoHandler = errors.New("route: no handler")
+)
+
+// RouteError 注册路由失败时返回的错误
+type RouteError struct {
+	Err      error  // 失败的原因 例如 ErrDuplicateRoute
+	Pattern  string // 注册的路由规则
+	Existing string // 与之冲突的已注册路由规则，仅 ErrDuplicateRoute 时存在
+	msg      string
+}
+
+func newRouteError(err error, pattern, existing, msg string) *RouteError {
+	return &RouteError{Err: err, Pattern: pattern, Existing: existing, msg: msg}
+}
+
+func (e *RouteError) Error() string {
+	return e.msg
+}
+
+func (e *RouteError) Unwrap() error {
+	return e.Err
+}
+
+// 结构化错误的错误码，前端可以根据错误码区分错误
+const (
+	CodeBadRequest     = "bad_request"      // 请求不合法
+	CodeBadParam       = "bad_param"        // 路由参数、查询参数或请求体字段不合法
+	CodeUnauthorized   = "unauthorized"     // 未认证
+	CodeForbidden      = "forbidden"        // 没有权限
+	CodeNotFound       = "not_found"        // 没有匹配到路由
+	CodeKindNotAllowed = "kind_not_allowed" // 只在其他操作类型下注册了路由
+	CodePanic          = "panic"            // handler 发生 panic
+	CodeInternal       = "internal"         // 其他错误
+)
+
+// Error 结构化的错误，由 ErrorHandler 渲染到响应体中
+// handler 可以直接记录 *Error 来指定错误码和附加信息
+//
+//	ctx.AbortWithError(http.StatusConflict, &route.Error{Code: "name_taken", Message: "name is already taken"})
+type Error struct {
+	Code    string      `json:"code"`              // 错误码 eg: not_found
+	Message string      `json:"message"`           // 错误信息
+	Details interface{} `json:"details,omitempty"` // 附加信息 eg: bad_param 时为 []ParamDetail
+	Cause   error       `json:"-"`                 // 原始错误
+}
+
+func (e *Error) Error() string {
+	if e.Cause != nil && e.Cause.Error() != e.Message {
+		return e.Message + ": " + e.Cause.Error()
+	}
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Cause
+}
+
+// ParamDetail bad_param 错误中单个参数的信息
+type ParamDetail struct {
+	Field  string `json:"field,omitempty"` // 绑定失败的结构体字段
+	Source string `json:"source"`          // 参数的来源 path、query、json、default
+	Name   string `json:"name"`            // 参数名
+	Value  string `json:"value"`           // 原始值
+	Reason string `json:"reason"`          // 不合法的原因
+}
+
+// AsError 将 err 转换为 *Error，status 为响应的状态码
+// err 本身是 *Error 时直接返回，路由错误、参数错误、绑定错误转换为对应的错误码
+// 其他错误根据 status 确定错误码
+func AsError(err error, status int) *Error {
+	var e *Error
+	if errors.As(err, &e) {
+		return e
+	}
+
+	var (
+		paramErr *server.ParamError
+		bindErr  *server.BindError
+	)
+	switch {
+	case errors.Is(err, ErrNoRoute):
+		return &Error{Code: CodeNotFound, Message: err.Error(), Cause: err}
+	case errors.Is(err, ErrKindNotAllowed):
+		return &Error{Code: CodeKindNotAllowed, Message: err.Error(), Cause: err}
+	case errors.As(err, &paramErr):
+		return &Error{Code: CodeBadParam, Message: err.Error(), Cause: err, Details: []ParamDetail{{
+			Source: "path",
+			Name:   paramErr.Name,
+			Value:  paramErr.Value,
+			Reason: paramErr.Err.Error(),
+		}}}
+	case errors.As(err, &bindErr):
+		details := make([]ParamDetail, len(bindErr.Errors))
+		for i, fe := range bindErr.Errors {
+			details[i] = ParamDetail{Field: fe.Field, Source: fe.Source, Name: fe.Name, Value: fe.Value, Reason: fe.Err.Error()}
+		}
+		return &Error{Code: CodeBadParam, Message: err.Error(), Cause: err, Details: details}
+	}
+	return &Error{Code: codeForStatus(status), Message: err.Error(), Cause: err}
+}
+
+// codeForStatus 根据状态码确定错误码
+func codeForStatus(status int) string {
+	switch status {
+	case http.StatusBadRequest:
+		return CodeBadRequest
+	case http.StatusUnauthorized:
+		return CodeUnauthorized
+	case http.StatusForbidden:
+		return CodeForbidden
+	case http.StatusNotFound:
+		return CodeNotFound
+	case http.StatusMethodNotAllowed:
+		return CodeKindNotAllowed
+	}
+	if status >= 400 && status < 500 {
+		return CodeBadRequest
+	}
+	return CodeInternal
+}
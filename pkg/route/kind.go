@@ -0,0 +1,23 @@
+package route
+
+// 路由的操作类型
+// 同一个 path 可以在不同操作类型下注册不同的 handler，每种操作类型对应一棵 RadixTree
+// 操作类型可以是任意非空字符串，例如 GET、POST，以下是内置的几种
+const (
+	KindQuery     = "QUERY"     // 查询 不修改状态 默认的操作类型
+	KindCommand   = "COMMAND"   // 命令 修改状态
+	KindSubscribe = "SUBSCRIBE" // 订阅
+)
+
+// KindTrees 所有操作类型的路由树
+type KindTrees []*RadixTree
+
+// get 返回 kind 对应的路由树，不存在时返回 nil
+func (trees KindTrees) get(kind string) *RadixTree {
+	for _, tree := range trees {
+		if tree.kind == kind {
+			return tree
+		}
+	}
+	return nil
+}
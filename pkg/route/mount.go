@@ -0,0 +1,128 @@
+package route
+
+import (
+	"context"
+	"github.com/Yuki-J1/wailsrouter/pkg/app/server"
+	"net/url"
+	"strings"
+)
+
+// mountParam Mount 内部使用的 * 参数名
+const mountParam = "mountpath"
+
+// Mount 将 prefix 下的所有请求交给 child 处理
+// 先执行当前路由组的中间件，再以去掉 prefix 后的本地路径在 child 中分发
+// 例如挂载到 /admin 后，请求 /admin/user/1 在 child 中的 path 为 /user/1
+// 内置的操作类型以及挂载时 child 已有的操作类型会被挂载
+// 挂载之后 child 才注册的自定义操作类型不会被挂载，需要在 Mount 之前注册
+// 错误和 panic 由最外层的 Engine 处理，child 的 ErrorHandler 和 PanicHandler 不会执行
+// child 重定向时写入的 Response.Location 会加上 prefix 匹配的原始 path，例如 /admin/user/1/ 重定向到 /admin/user/1
+func (group *RouterGroup) Mount(prefix string, child *Engine) {
+	absolutePrefix := group.calculateAbsolutePath(prefix)
+	engine := group.engine
+	handler := func(c context.Context, ctx *server.RequestContext) {
+		prefixPath, localPath := mountedPath(ctx.Path, absolutePrefix, engine.UseRawPath)
+		serveMounted(c, ctx, prefixPath, localPath, child)
+	}
+
+	kinds := []string{KindQuery, KindCommand, KindSubscribe}
+	for _, tree := range child.loadTrees() {
+		if !containsKind(kinds, tree.kind) {
+			kinds = append(kinds, tree.kind)
+		}
+	}
+	for _, kind := range kinds {
+		group.handle(kind, prefix, server.HandlersChain{handler})
+		group.handle(kind, joinPaths(prefix, "/*"+mountParam), server.HandlersChain{handler})
+	}
+}
+
+// mountedPath 从原始 path 中去掉 prefix 匹配的部分，返回 prefix 匹配的原始 path 和 child 中的本地路径
+// prefix 中的每个路径段 (包括参数) 都对应查找时 path 的一个路径段
+// 默认模式下查找使用解码后的 path，需要将解码后的长度换算为原始 path 的长度
+// 本地路径保持原始编码，由 child 按照自己的配置解码，参数值不会被重复解码
+func mountedPath(path []byte, prefix string, useRawPath bool) (prefixPath, localPath []byte) {
+	segments := strings.Count(strings.TrimSuffix(prefix, slash), slash)
+	lookupPath := string(path)
+	decoded := false
+	if !useRawPath {
+		if p, err := url.PathUnescape(lookupPath); err == nil {
+			lookupPath, decoded = p, true
+		}
+	}
+
+	// 查找时 path 中 prefix 匹配的长度
+	n := 0
+	for i := 0; i < segments; i++ {
+		j := strings.IndexByte(lookupPath[n+1:], '/')
+		if j == -1 {
+			n = len(lookupPath)
+			break
+		}
+		n += j + 1
+	}
+	// 换算为原始 path 中的长度，%XX 解码后为一个字节
+	if decoded {
+		i := 0
+		for k := 0; k < n; k++ {
+			if path[i] == '%' {
+				i += 3
+			} else {
+				i++
+			}
+		}
+		n = i
+	}
+
+	rest := path[n:]
+	// 被解码为 / 的 %2F 同样作为本地路径的开头
+	if len(rest) >= 3 && rest[0] == '%' {
+		rest = append([]byte(slash), rest[3:]...)
+	}
+	if len(rest) == 0 || rest[0] != '/' {
+		return path[:n], []byte(slash)
+	}
+	return path[:n], rest
+}
+
+// serveMounted 改写请求上下文后交给 child 分发，结束后恢复
+// child 中的路径都是本地路径，child 写入的 Response.Location 需要加上 prefixPath
+func serveMounted(c context.Context, ctx *server.RequestContext, prefixPath, localPath []byte, child *Engine) {
+	var (
+		location = ctx.Response.Location
+		path     = ctx.Path
+		params   = append(server.Params(nil), ctx.Params...)
+		fullPath = ctx.FullPath()
+		handlers = ctx.Handlers()
+		index    = ctx.GetIndex()
+	)
+
+	ctx.Path = localPath
+	ctx.Params = ctx.Params[:0]
+	ctx.SetFullPath(nilString)
+	ctx.SetIndex(-1)
+	child.serve(c, ctx)
+	aborted := ctx.IsAborted()
+	if ctx.Response.Location != location && strings.HasPrefix(ctx.Response.Location, slash) {
+		ctx.Response.Location = string(prefixPath) + ctx.Response.Location
+	}
+
+	ctx.Path = path
+	ctx.Params = append(ctx.Params[:0], params...)
+	ctx.SetFullPath(fullPath)
+	ctx.SetHandlers(handlers)
+	ctx.SetIndex(index)
+	// child 中止了调用链 当前调用链同样中止
+	if aborted {
+		ctx.Abort()
+	}
+}
+
+func containsKind(kinds []string, kind string) bool {
+	for _, k := range kinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
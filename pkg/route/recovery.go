@@ -0,0 +1,94 @@
+package route
+
+import (
+	"context"
+	"fmt"
+	"github.com/Yuki-J1/wailsrouter/pkg/app/server"
+	"github.com/cloudwego/hertz/pkg/common/hlog"
+	"net/http"
+	"runtime/debug"
+)
+
+// PanicError handler 发生 panic 时记录的原始错误，作为 Error.Cause 保存在 ctx.Errors 中
+// PanicHandler 和 ErrorHandler 可以通过 errors.As 获取 panic 的值和调用栈
+//
+//	var pe *route.PanicError
+//	if errors.As(ctx.Errors.Last(), &pe) { ... }
+type PanicError struct {
+	Value interface{} // recover 返回的值
+	Stack []byte      // 发生 panic 时的调用栈
+	Route string      // 匹配到的路由规则，没有匹配到路由时为空
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprint(e.Value)
+}
+
+// Unwrap panic 的值是 error 时返回该值
+func (e *PanicError) Unwrap() error {
+	err, _ := e.Value.(error)
+	return err
+}
+
+// RecoveryHandler 处理 panic，err 为 recover 返回的值，stack 为发生 panic 时的调用栈
+type RecoveryHandler func(c context.Context, ctx *server.RequestContext, err interface{}, stack []byte)
+
+// RecoveryOption Recovery 的配置项
+type RecoveryOption func(o *recoveryOptions)
+
+type recoveryOptions struct {
+	handler RecoveryHandler
+	repanic bool
+}
+
+// WithRecoveryHandler 记录错误并输出日志后执行 handler，可以在 handler 中改写响应
+func WithRecoveryHandler(handler RecoveryHandler) RecoveryOption {
+	return func(o *recoveryOptions) {
+		o.handler = handler
+	}
+}
+
+// WithRepanic 处理完成后以原来的值重新 panic，便于在测试中暴露 panic
+// 此时外层不能再有 Recovery 或 Engine.PanicHandler，否则同一个 panic 会被记录两次
+func WithRepanic() RecoveryOption {
+	return func(o *recoveryOptions) {
+		o.repanic = true
+	}
+}
+
+// Recovery 返回捕获后续 handler panic 的中间件
+// 捕获到 panic 时中止调用链，将状态码设置为 500，向 ctx.Errors 记录错误码为 panic 的 *Error
+// 并输出包含路由规则和调用栈的日志，再执行 WithRecoveryHandler 设置的 handler
+//
+// Engine.PanicHandler 为 nil 时 Engine 不会捕获 panic，Recovery 是唯一的捕获方式
+//
+//	engine.Use(route.Recovery())
+func Recovery(opts ...RecoveryOption) server.HandlerFunc {
+	o := &recoveryOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return func(c context.Context, ctx *server.RequestContext) {
+		defer func() {
+			if rcv := recover(); rcv != nil {
+				pe := recoverPanic(c, ctx, rcv)
+				if o.handler != nil {
+					o.handler(c, ctx, pe.Value, pe.Stack)
+				}
+				if o.repanic {
+					panic(rcv)
+				}
+			}
+		}()
+		ctx.Next(c)
+	}
+}
+
+// recoverPanic 中止调用链并记录 panic，返回记录的 *PanicError
+func recoverPanic(c context.Context, ctx *server.RequestContext, rcv interface{}) *PanicError {
+	pe := &PanicError{Value: rcv, Stack: debug.Stack(), Route: ctx.FullPath()}
+	ctx.AbortWithStatus(http.StatusInternalServerError)
+	ctx.Error(&Error{Code: CodePanic, Message: pe.Error(), Cause: pe})
+	hlog.CtxErrorf(c, "[Recovery] route=%s path=%s err=%v\nstack=%s", pe.Route, ctx.Path, rcv, pe.Stack)
+	return pe
+}
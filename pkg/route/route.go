@@ -0,0 +1,124 @@
+package route
+
+import (
+	"fmt"
+	"github.com/Yuki-J1/wailsrouter/pkg/app/server"
+	"net/url"
+	"strings"
+)
+
+// Route 一条已注册路由的句柄，由 RouterGroup.Handle 等方法返回
+type Route struct {
+	engine *Engine
+	kind   string // 操作类型
+	path   string // 注册时的路由规则
+	name   string // 路由名称
+}
+
+// Kind 返回路由的操作类型
+func (r *Route) Kind() string {
+	return r.kind
+}
+
+// Path 返回注册时的路由规则
+func (r *Route) Path() string {
+	return r.path
+}
+
+// Name 为路由命名，命名后可以通过 Engine.URLFor 反向生成 path
+// 名称为空或已被其他路由使用时 panic
+func (r *Route) Name(name string) *Route {
+	r.engine.mu.Lock()
+	defer r.engine.mu.Unlock()
+
+	if name == "" {
+		panic("route name should not be ''")
+	}
+	if _, ok := r.engine.names[name]; ok {
+		panic("route name '" + name + "' is already registered")
+	}
+	if r.name != "" {
+		delete(r.engine.names, r.name)
+	}
+	r.name = name
+	r.engine.names[name] = r
+	return r
+}
+
+// URLFor 根据路由名称和参数反向生成 path
+// 例如路由 /user/:name/*filepath 命名为 file，URLFor("file", Param{"name", "ykj"}, Param{"filepath", "a/b.txt"}) 返回 /user/ykj/a/b.txt
+// 可选参数 /user/:name? 没有值时返回 /user
+// 参数值会被转义，: 参数值包含 / 时无法还原，与名称不存在、缺少参数一样返回错误
+func (engine *Engine) URLFor(name string, params ...server.Param) (string, error) {
+	engine.mu.RLock()
+	r, ok := engine.names[name]
+	engine.mu.RUnlock()
+	if !ok {
+		return "", fmt.Errorf("%w: '%s'", ErrRouteNameNotFound, name)
+	}
+	ps := server.Params(params)
+
+	var sb strings.Builder
+	path := r.path
+	for i := 0; i < len(path); i++ {
+		c := path[i]
+		if c != paramLabel && c != anyLabel {
+			sb.WriteByte(c)
+			continue
+		}
+
+		// 参数名到下一个 / 或约束为止，* 一定在结尾
+		j := i + 1
+		for ; j < len(path) && path[j] != '/'; j++ {
+		}
+		nameEnd := j
+		if c == paramLabel {
+			nameEnd, j, _ = wildcardEnd(path, i)
+		}
+		pname := path[i+1 : nameEnd]
+		value, ok := ps.Get(pname)
+		// 可选参数没有值时省略最后一个路径段 eg: /user/:name? > /user
+		if j < len(path) && path[j] == optionalLabel && value == "" {
+			if prefix := sb.String(); len(prefix) > 1 {
+				return strings.TrimSuffix(prefix, slash), nil
+			}
+			return sb.String(), nil
+		}
+		if !ok || (c == paramLabel && value == "") {
+			return "", fmt.Errorf("%w: '%s' in route '%s'", ErrMissingParam, pname, path)
+		}
+
+		// : 参数值不能跨越路径段，即使转义为 %2F，默认模式下查找前也会被解码为 /
+		if c == paramLabel && strings.IndexByte(value, '/') != -1 {
+			return "", fmt.Errorf("%w: '%s' of param '%s' in route '%s'", ErrInvalidParam, value, pname, path)
+		}
+		// 参数值不能包含紧跟在参数之后的分隔符，否则会被提前截断 eg: /files/:name.:ext
+		if c == paramLabel && j < len(path) && path[j] != '/' && path[j] != optionalLabel && strings.IndexByte(value, path[j]) != -1 {
+			return "", fmt.Errorf("%w: '%s' of param '%s' contains delimiter '%c' in route '%s'", ErrInvalidParam, value, pname, path[j], path)
+		}
+		// 参数值必须满足约束 eg: /user/:id<int>
+		if j > nameEnd {
+			if constraint, err := newConstraint(path[nameEnd+1 : j-1]); err == nil && !constraint.match(value) {
+				return "", fmt.Errorf("%w: '%s' of param '%s' does not match constraint '%s' in route '%s'", ErrInvalidParam, value, pname, constraint.expr, path)
+			}
+		}
+		// 转义参数值，Serve 解码后得到原始值 eg: 50% off > 50%25%20off
+		// * 参数值逐段转义，保留其中的 /
+		if c == anyLabel {
+			for k, seg := range strings.Split(value, slash) {
+				if k > 0 {
+					sb.WriteString(slash)
+				}
+				sb.WriteString(url.PathEscape(seg))
+			}
+		} else {
+			sb.WriteString(url.PathEscape(value))
+		}
+		i = j - 1
+		// 跳过可选参数标记
+		if j < len(path) && path[j] == optionalLabel {
+			i = j
+		}
+	}
+	return sb.String(), nil
+}
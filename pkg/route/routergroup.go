@@ -8,11 +8,19 @@ import (
 type IRouter interface {
 	IRoutes
 	Group(string, ...server.HandlerFunc) *RouterGroup
+	TryGroup(string, ...server.HandlerFunc) (*RouterGroup, error)
+	Mount(string, *Engine)
 }
 
 type IRoutes interface {
 	Use(...server.HandlerFunc) IRoutes
-	Handle(string, ...server.HandlerFunc) IRoutes
+	Handle(string, ...server.HandlerFunc) *Route
+	HandleKind(string, string, ...server.HandlerFunc) *Route
+	Query(string, ...server.HandlerFunc) *Route
+	Command(string, ...server.HandlerFunc) *Route
+	Subscribe(string, ...server.HandlerFunc) *Route
+	TryHandle(string, ...server.HandlerFunc) (*Route, error)
+	TryHandleKind(string, string, ...server.HandlerFunc) (*Route, error)
 }
 
 var _ IRouter = (*RouterGroup)(nil)
@@ -35,18 +43,64 @@ func (group *RouterGroup) Use(middleware ...server.HandlerFunc) IRoutes {
 // endregion
 
 // region ========== Handle ==========
-func (group *RouterGroup) Handle(relativePath string, handlers ...server.HandlerFunc) IRoutes {
-	return group.handle(relativePath, handlers)
+
+// Handle 以默认的操作类型 KindQuery 注册路由，返回的 Route 可以用于命名路由
+func (group *RouterGroup) Handle(relativePath string, handlers ...server.HandlerFunc) *Route {
+	return group.handle(KindQuery, relativePath, handlers)
+}
+
+// HandleKind 以指定的操作类型注册路由
+func (group *RouterGroup) HandleKind(kind, relativePath string, handlers ...server.HandlerFunc) *Route {
+	return group.handle(kind, relativePath, handlers)
+}
+
+// Query 是 HandleKind(KindQuery, path, handlers...) 的简写
+func (group *RouterGroup) Query(relativePath string, handlers ...server.HandlerFunc) *Route {
+	return group.handle(KindQuery, relativePath, handlers)
+}
+
+// Command 是 HandleKind(KindCommand, path, handlers...) 的简写
+func (group *RouterGroup) Command(relativePath string, handlers ...server.HandlerFunc) *Route {
+	return group.handle(KindCommand, relativePath, handlers)
 }
 
-func (group *RouterGroup) handle(relativePath string, handlers server.HandlersChain) IRoutes {
+// Subscribe 是 HandleKind(KindSubscribe, path, handlers...) 的简写
+func (group *RouterGroup) Subscribe(relativePath string, handlers ...server.HandlerFunc) *Route {
+	return group.handle(KindSubscribe, relativePath, handlers)
+}
+
+// TryHandle 与 Handle 相同，但注册失败时返回 *RouteError 而不是 panic
+// 适用于运行时加载的路由，不合法的路由不会导致程序崩溃
+func (group *RouterGroup) TryHandle(relativePath string, handlers ...server.HandlerFunc) (*Route, error) {
+	return group.tryHandle(KindQuery, relativePath, handlers)
+}
+
+// TryHandleKind 与 HandleKind 相同，但注册失败时返回 *RouteError 而不是 panic
+func (group *RouterGroup) TryHandleKind(kind, relativePath string, handlers ...server.HandlerFunc) (*Route, error) {
+	return group.tryHandle(kind, relativePath, handlers)
+}
+
+func (group *RouterGroup) handle(kind, relativePath string, handlers server.HandlersChain) *Route {
+	r, err := group.tryHandle(kind, relativePath, handlers)
+	if err != nil {
+		panic(err.Error())
+	}
+	return r
+}
+
+func (group *RouterGroup) tryHandle(kind, relativePath string, handlers server.HandlersChain) (*Route, error) {
 	// 整合 完整路径
 	absolutePath := group.calculateAbsolutePath(relativePath)
 	// 整合 完整handlers
-	handlers = group.combineHandlers(handlers)
+	handlers, err := group.tryCombineHandlers(absolutePath, handlers)
+	if err != nil {
+		return nil, err
+	}
 	// 添加路由
-	group.engine.addRoute(absolutePath, handlers)
-	return group.returnObj()
+	if err = group.engine.tryAddRoute(kind, absolutePath, handlers, len(group.Handlers)); err != nil {
+		return nil, err
+	}
+	return &Route{engine: group.engine, kind: kind, path: absolutePath}, nil
 }
 
 // 组合AbsolutePath
@@ -76,14 +130,24 @@ func lastChar(str string) uint8 {
 	return str[len(str)-1]
 }
 
-// 组合Handlers
+// 组合Handlers，失败时 panic
 func (group *RouterGroup) combineHandlers(handlers server.HandlersChain) server.HandlersChain {
+	mergedHandlers, err := group.tryCombineHandlers(group.basePath, handlers)
+	if err != nil {
+		panic(err.Error())
+	}
+	return mergedHandlers
+}
+
+// 组合Handlers，失败时返回 *RouteError
+// pattern 仅用于错误信息
+func (group *RouterGroup) tryCombineHandlers(pattern string, handlers server.HandlersChain) (server.HandlersChain, error) {
 	// = 当前路由组已有的handler长度 + 此次需要组合的handler长度
 	// 计算组合后的大小
 	finalSize := len(group.Handlers) + len(handlers)
-	// 限制handlers中handler个数最多为63个
+	// 限制handlers中handler个数最多为63个 (RequestContext.Abort 依赖该上限)
 	if finalSize >= 63 {
-		panic("too many handlers")
+		return nil, newRouteError(ErrTooManyHandlers, pattern, "", "too many handlers")
 	}
 	// 创建指定大小 server.HandlersChain 类型 实例
 	mergedHandlers := make(server.HandlersChain, finalSize)
@@ -91,7 +155,7 @@ func (group *RouterGroup) combineHandlers(handlers server.HandlersChain) server.
 	copy(mergedHandlers, group.Handlers)
 	// 再将 此次需要组合的handler 添加到 handlers
 	copy(mergedHandlers[len(group.Handlers):], handlers)
-	return mergedHandlers
+	return mergedHandlers, nil
 }
 
 // endregion
@@ -100,11 +164,25 @@ func (group *RouterGroup) combineHandlers(handlers server.HandlersChain) server.
 
 // Group 根据当前路由组的基础路径、基础handler 创建新的子路由组。
 func (group *RouterGroup) Group(relativePath string, handlers ...server.HandlerFunc) *RouterGroup {
+	g, err := group.TryGroup(relativePath, handlers...)
+	if err != nil {
+		panic(err.Error())
+	}
+	return g
+}
+
+// TryGroup 与 Group 相同，但中间件过多时返回 *RouteError 而不是 panic
+func (group *RouterGroup) TryGroup(relativePath string, handlers ...server.HandlerFunc) (*RouterGroup, error) {
+	basePath := group.calculateAbsolutePath(relativePath)
+	mergedHandlers, err := group.tryCombineHandlers(basePath, handlers)
+	if err != nil {
+		return nil, err
+	}
 	return &RouterGroup{
-		Handlers: group.combineHandlers(handlers),
-		basePath: group.calculateAbsolutePath(relativePath),
+		Handlers: mergedHandlers,
+		basePath: basePath,
 		engine:   group.engine,
-	}
+	}, nil
 }
 
 // endregion
@@ -6,25 +6,32 @@ import (
 	"github.com/Yuki-J1/wailsrouter/pkg/app/server"
 	"net/url"
 	"strings"
+	"unicode/utf8"
 )
 
 type RadixTree struct {
+	kind string // 操作类型
 	root *node
 }
 
+// 路由树的快照之间共享节点，因此节点上不保存父节点，修改时沿路径复制节点 (copy-on-write)
 type (
 	node struct {
-		kind       kind
-		label      byte
-		prefix     string
-		parent     *node
-		children   children
-		ppath      string
-		pnames     []string
-		handlers   server.HandlersChain
-		paramChild *node
-		anyChild   *node
-		isLeaf     bool
+		kind     kind
+		label    byte
+		prefix   string
+		children children
+		ppath    string
+		pnames   []string
+		handlers server.HandlersChain
+		// paramChildren 同一位置的 : 参数节点，按约束区分
+		// 有约束的在前 (按注册顺序)，没有约束的最多一个并且在最后，查找时依次尝试
+		paramChildren children
+		anyChild      *node
+		isLeaf        bool
+		constraint    *paramConstraint // : 参数节点的类型约束
+		// middlewares handlers 中注册时所在路由组中间件的个数，Replace 时保留这部分 handler
+		middlewares int
 	}
 	kind     uint8
 	children []*node
@@ -39,81 +46,167 @@ const (
 	akind
 	paramLabel = byte(':')
 	anyLabel   = byte('*')
-	slash      = "/"
-	nilString  = ""
+	// 可选参数标记 eg: /user/:name?
+	optionalLabel = byte('?')
+	slash         = "/"
+	nilString     = ""
 )
 
-// checkPahtValid 对path进行检查，如果不符合规范，panic
-func checkPahtValid(path string) {
+// checkPahtValid 对path进行检查，如果不符合规范，返回 *RouteError
+func checkPahtValid(path string) error {
 	// path 不能为空
 	if path == nilString {
-		panic("empty path")
+		return newRouteError(ErrInvalidPath, path, nilString, "empty path")
 	}
 
 	// path 必须以 '/' 开头
 	if path[0] != '/' {
-		panic("path must begin with '/'")
+		return newRouteError(ErrInvalidPath, path, nilString, "path must begin with '/'")
 	}
 
-	for i, c := range []byte(path) {
-		switch c {
+	for i := 0; i < len(path); i++ {
+		switch path[i] {
 		// eg1：/user/:name/ 允许
 		// eg2：/user/:/ 不允许
 		// eg3：/user/:	不允许
-
-		// eg4：/user/* 不允许
-		// eg5：/user*name/ 不允许
-		// eg6：/user/*name/ 不允许
+		// eg4：/user/:id<int>/ 允许
+		// eg5：/user/:id<int 不允许
+		// eg6：/user/:name? 允许
+		// eg7：/user/:name?/posts 不允许
+		// eg8：/files/:name.:ext 允许
+		// eg9：/:foo:bar 不允许
+
+		// eg10：/user/* 不允许
+		// eg11：/user*name/ 不允许
+		// eg12：/user/*name/ 不允许
 		case ':':
-			// 那么在第6次循环时，c == ':'，i == 5
+			// 那么在第6次循环时，path[i] == ':'，i == 5
+			nameEnd, end, ok := wildcardEnd(path, i)
 
-			// :后面紧接着/ 或 只有: 都会panic
-			if (i < len(path)-1 && path[i+1] == '/') /* :后面是/ */ || /* 或 */ i == (len(path)-1) /* 只有: */ {
-				panic("wildcards must be named with a non-empty name in path '" + path + "'")
+			// :后面紧接着/ 或 只有: 或 只有约束 都会返回错误
+			if nameEnd == i+1 {
+				return newRouteError(ErrInvalidWildcard, path, nilString, "wildcards must be named with a non-empty name in path '"+path+"'")
 			}
-			i++
-			// 分隔符之间只能存在一个 : 或 * 否则 panic
-			for ; i < len(path) && path[i] != '/'; i++ {
-				if path[i] == ':' || path[i] == '*' {
-					panic("only one wildcard per path segment is allowed, find multi in path '" + path + "'")
+			// 约束必须闭合 并且不能为空
+			if !ok || end-nameEnd == 2 {
+				return newRouteError(ErrInvalidWildcard, path, nilString, "invalid constraint for wildcard '"+path[i:end]+"' in path '"+path+"'")
+			}
+			if end > nameEnd {
+				if _, err := newConstraint(path[nameEnd+1 : end-1]); err != nil {
+					return newRouteError(ErrInvalidWildcard, path, nilString, "invalid constraint for wildcard '"+path[i:end]+"' in path '"+path+"': "+err.Error())
+				}
+			}
+			if end == len(path) {
+				return nil
+			}
+			switch path[end] {
+			// 可选参数只能是最后一个路径段
+			case optionalLabel:
+				if end != len(path)-1 {
+					return newRouteError(ErrInvalidWildcard, path, nilString, "optional wildcard must be the last path segment in path '"+path+"'")
 				}
+				return nil
+			// 参数之后紧跟 : 或 * 时无法确定参数值的边界，参数之间必须存在分隔符
+			// eg: /:foo:bar 不允许  /:from-:to 允许
+			case ':', '*':
+				return newRouteError(ErrInvalidWildcard, path, nilString, "only one wildcard per path segment is allowed unless separated by a delimiter, find multi in path '"+path+"'")
+			// 约束只能紧跟在参数名之后
+			case '<', '>':
+				return newRouteError(ErrInvalidWildcard, path, nilString, "invalid constraint for wildcard '"+path[i:end+1]+"' in path '"+path+"'")
 			}
+			i = end - 1
+		case optionalLabel:
+			// ? 只能紧跟在最后一个 : 参数之后
+			return newRouteError(ErrInvalidWildcard, path, nilString, "'?' is only allowed after the last wildcard in path '"+path+"'")
 		case '*':
 			// * 之后必须存在
-			// /user/* 引发panic
+			// /user/* 返回错误
 			if i == len(path)-1 {
-				panic("wildcards must be named with a non-empty name in path '" + path + "'")
+				return newRouteError(ErrInvalidWildcard, path, nilString, "wildcards must be named with a non-empty name in path '"+path+"'")
 			}
 			// * 之前必须是 /
-			// /user*name/ 引发panic
+			// /user*name/ 返回错误
 			if i > 0 && path[i-1] != '/' {
-				panic(" no / before wildcards in path " + path)
+				return newRouteError(ErrInvalidWildcard, path, nilString, " no / before wildcards in path "+path)
 			}
 			// * 之后必须没有 /
-			// /user/*name/ 引发panic
+			// /user/*name/ 返回错误
 			for ; i < len(path); i++ {
 				if path[i] == '/' {
-					panic("catch-all routes are only allowed at the end of the path in path '" + path + "'")
+					return newRouteError(ErrInvalidWildcard, path, nilString, "catch-all routes are only allowed at the end of the path in path '"+path+"'")
+				}
+				// * 参数本身可以匹配空值，不能是可选参数
+				if path[i] == optionalLabel {
+					return newRouteError(ErrInvalidWildcard, path, nilString, "catch-all routes can not be optional in path '"+path+"'")
 				}
 			}
 		}
 
 	}
+	return nil
 }
 
+// addRoute 添加路由，失败时 panic
 func (r *RadixTree) addRoute(path string, h server.HandlersChain) {
-	// 对path进行检查，如果不符合规范，panic
-	checkPahtValid(path)
+	if err := r.tryAddRoute(path, h, 0); err != nil {
+		panic(err.Error())
+	}
+}
 
-	var (
-		pnames []string // Param names
-		ppath  = path   // Pristine path
-	)
+// tryAddRoute 添加路由，失败时返回 *RouteError
+// middlewares 为 h 中路由组中间件的个数，Replace 时保留
+// 可选参数 /user/:name? 会展开为 /user/:name 和 /user 两个路由，共用 handlers 和 ppath
+func (r *RadixTree) tryAddRoute(path string, h server.HandlersChain, middlewares int) error {
+	// 对path进行检查，如果不符合规范，返回错误
+	if err := checkPahtValid(path); err != nil {
+		return err
+	}
 
 	// 路由规则对应的处理函数不能为空
 	if h == nil {
-		panic(fmt.Sprintf("Adding route without handler function: %v", path))
+		return newRouteError(ErrNoHandler, path, nilString, fmt.Sprintf("Adding route without handler function: %v", path))
+	}
+
+	full, short, optional := expandOptional(path)
+	if !optional {
+		return withPattern(r.addPath(path, path, h, middlewares), path)
+	}
+	if err := r.addPath(full, path, h, middlewares); err != nil {
+		return withPattern(err, path)
+	}
+	return withPattern(r.addPath(short, path, h, middlewares), path)
+}
+
+// expandOptional 展开结尾的可选参数
+// eg: /user/:name? 返回 /user/:name 和 /user  /:name? 返回 /:name 和 /
+func expandOptional(path string) (full, short string, ok bool) {
+	if path[len(path)-1] != optionalLabel {
+		return path, nilString, false
+	}
+	full = path[:len(path)-1]
+	// 最后一个路径段中的 : 参数
+	i := strings.LastIndexByte(full, '/')
+	short = full[:i+strings.IndexByte(full[i:], paramLabel)]
+	if len(short) > 1 && short[len(short)-1] == '/' {
+		short = short[:len(short)-1]
+	}
+	return full, short, true
+}
+
+// withPattern 中间节点插入时没有 ppath，为错误补充注册的路由规则
+func withPattern(err error, pattern string) error {
+	if re, ok := err.(*RouteError); ok && re.Pattern == nilString {
+		re.Pattern = pattern
 	}
+	return err
+}
+
+// addPath 将不含可选参数的 path 添加到路由树，ppath 为注册时的原始路由规则
+func (r *RadixTree) addPath(path, ppath string, h server.HandlersChain, middlewares int) error {
+	var (
+		pnames      []string           // Param names
+		constraints []*paramConstraint // 与 pnames 一一对应的参数约束，没有约束时为 nil
+	)
 
 	for i /* 递增指针 */, lcpIndex /* path长度 */ := 0, len(path); i < lcpIndex; i++ {
 		// 第一种情况：在path中当遇到:
@@ -122,15 +215,26 @@ func (r *RadixTree) addRoute(path string, h server.HandlersChain) {
 			// j 表示:后面的字符位置
 			j := i + 1
 			// 先插入:前面的部分 /user/
-			r.insert(path[:i], nil, skind, nilString, nil)
-			// 将i指向下一个 / 字符 或 i指向path结尾
-			for ; i < lcpIndex && path[i] != '/'; i++ {
+			if err := r.insert(path[:i], nil, 0, skind, nilString, nil, constraints); err != nil {
+				return err
 			}
+			// nameEnd 指向参数名结尾 i指向参数(包含约束)之后的 / 字符 或 i指向path结尾
+			nameEnd, end, _ := wildcardEnd(path, i)
+			i = end
 			// 将参数name添加到参数列表
-			// path[j:i] 表示:到/之间后面参数部分
-			pnames = append(pnames, path[j:i])
+			// path[j:nameEnd] 表示:到/或<之间的参数名部分
+			pnames = append(pnames, path[j:nameEnd])
+			// 参数的类型约束 eg: /user/:id<int>
+			var constraint *paramConstraint
+			if end > nameEnd {
+				var err error
+				if constraint, err = newConstraint(path[nameEnd+1 : end-1]); err != nil {
+					return newRouteError(ErrInvalidWildcard, ppath, nilString, err.Error())
+				}
+			}
+			constraints = append(constraints, constraint)
 
-			// path 变成没有参数 /user/:name > /user/:
+			// path 变成没有参数 /user/:name > /user/:  /user/:id<int> > /user/:
 			path = path[:j] + path[i:]
 
 			// i path中指向:后面的字符的下标
@@ -140,41 +244,48 @@ func (r *RadixTree) addRoute(path string, h server.HandlersChain) {
 			// 说明原始插入字符串的结尾没有/
 			if i == lcpIndex {
 				// 插入 /user/:
-				r.insert(path[:i], h, pkind, nilString, pnames)
-				return
+				return r.insert(path[:i], h, middlewares, pkind, ppath, pnames, constraints)
 			} else
 			// 说明原始插入字符串的结尾有/
 			{
 				// 插入 /user/: 但没有 h
-				r.insert(path[:i], nil, pkind, nilString, pnames)
+				if err := r.insert(path[:i], nil, 0, pkind, nilString, pnames, constraints); err != nil {
+					return err
+				}
 			}
 		} else
 		// 第二种情况：在path中当遇到*
 		// 假设 /user/*name 此时i = 6
 		if path[i] == anyLabel {
 			// 插入 /user/ 无h
-			r.insert(path[:i], nil, skind, nilString, nil)
+			if err := r.insert(path[:i], nil, 0, skind, nilString, nil, constraints); err != nil {
+				return err
+			}
 			// 将参数 name 添加到参数列表
 			pnames = append(pnames, path[i+1:])
 			// 插入 /user/* 有h
-			r.insert(path[:i+1], h, akind, ppath, pnames)
-			return
+			return r.insert(path[:i+1], h, middlewares, akind, ppath, pnames, constraints)
 		}
 	}
 	// 第三种情况 : 插入的path是静态路由
-	r.insert(path, h, skind, ppath, pnames)
+	return r.insert(path, h, middlewares, skind, ppath, pnames, constraints)
 }
 
-func (r *RadixTree) insert(path string, h server.HandlersChain, t kind, ppath string, pnames []string) {
-	// currentNode 指向根节点
-	currentNode := r.root
-	// currentNode 为nil panic
-	if currentNode == nil {
-		panic("invalid node")
+// insert 插入节点，constraints 为 path 中每个 : 参数的约束，用于选择同一位置约束相同的参数节点
+// 经过的节点都会被复制后再修改，原有节点仍属于之前的快照，不会被修改
+func (r *RadixTree) insert(path string, h server.HandlersChain, middlewares int, t kind, ppath string, pnames []string, constraints []*paramConstraint) error {
+	// 根节点为nil 返回错误
+	if r.root == nil {
+		return newRouteError(ErrInvalidPath, ppath, nilString, "invalid node")
 	}
+	// currentNode 指向根节点的副本
+	r.root = r.root.copy()
+	currentNode := r.root
 	// path 是要插入的字符串原始样子
 	// search 是要被切割的字符串
 	search := path
+	// params 已经经过的 : 参数节点个数
+	params := 0
 
 	// https://i.miji.bid/2023/11/26/361e4cd59e1419909a7fe79a0b364110.png
 	for {
@@ -207,45 +318,34 @@ func (r *RadixTree) insert(path string, h server.HandlersChain, t kind, ppath st
 				currentNode.handlers = h
 				currentNode.ppath = ppath
 				currentNode.pnames = pnames
+				currentNode.middlewares = middlewares
 			}
 			// 当currentNode所指的节点 无子节点 无:节点 无*节点
 			// currentNode所指的节点才是叶子节点
-			currentNode.isLeaf = currentNode.children == nil && currentNode.paramChild == nil && currentNode.anyChild == nil
+			currentNode.isLeaf = currentNode.children == nil && currentNode.paramChildren == nil && currentNode.anyChild == nil
 		} else
 		// 当lcpLen(公共部分长度) 小于 currentNode所指的节点的前缀长度
 		if lcpLen < prefixLen {
 
 			// region ========== 分裂第一步 创建currentNode所指节点的副本 ==========
 
-			// 1. 副本和currentNode所指节点区别在于prefix其他不变
-			// 2. 副本的父节点是 currentNode所指节点
+			// 副本和currentNode所指节点区别在于prefix其他不变
 			n := newNode(
 				currentNode.kind,
 				currentNode.prefix[lcpLen:],
-				currentNode,
 				currentNode.children,
 				currentNode.handlers,
 				currentNode.ppath,
 				currentNode.pnames,
-				currentNode.paramChild,
+				currentNode.paramChildren,
 				currentNode.anyChild,
 			)
+			n.constraint = currentNode.constraint
+			n.middlewares = currentNode.middlewares
 
 			// endregion
 
-			// region ========== 分裂第二步 将currentNode所指节点的孩子父亲改为n(currentNode所指节点的副本) (子节点转移) (currentNode所指节点减去一层那么所有孩子也都减去一层)  ==========
-			for _, child := range currentNode.children {
-				child.parent = n
-			}
-			if currentNode.paramChild != nil {
-				currentNode.paramChild.parent = n
-			}
-			if currentNode.anyChild != nil {
-				currentNode.anyChild.parent = n
-			}
-			// endregion
-
-			// region ========== 分裂第三步 更新currentNode所指节点(可能被特殊情况重置) ==========
+			// region ========== 分裂第二步 更新currentNode所指节点(可能被特殊情况重置) ==========
 
 			currentNode.kind = skind
 			currentNode.label = currentNode.prefix[0]
@@ -254,13 +354,15 @@ func (r *RadixTree) insert(path string, h server.HandlersChain, t kind, ppath st
 			currentNode.handlers = nil
 			currentNode.ppath = nilString
 			currentNode.pnames = nil
-			currentNode.paramChild = nil
+			currentNode.paramChildren = nil
 			currentNode.anyChild = nil
 			currentNode.isLeaf = false
+			currentNode.constraint = nil
+			currentNode.middlewares = 0
 
 			// endregion
 
-			// region ========== 分裂第四步 连接  ==========
+			// region ========== 分裂第三步 连接  ==========
 			currentNode.children = append(currentNode.children, n)
 			// endregion
 
@@ -277,18 +379,20 @@ func (r *RadixTree) insert(path string, h server.HandlersChain, t kind, ppath st
 				currentNode.handlers = h
 				currentNode.ppath = ppath
 				currentNode.pnames = pnames
+				currentNode.middlewares = middlewares
 
 				// endregion
 			} else {
 				// region ========== 插入情况：还有多出一个子节点，保存本次插入的handlers ==========
 
 				// https://i.miji.bid/2023/11/26/38366484c8bddb3f01f14fc82b50a3a6.png
-				n = newNode(t, search[lcpLen:], currentNode, nil, h, ppath, pnames, nil, nil)
+				n = newNode(t, search[lcpLen:], nil, h, ppath, pnames, nil, nil)
+				n.middlewares = middlewares
 				currentNode.children = append(currentNode.children, n)
 				// endregion
 			}
 			// 判断currentNode所指节点是否为叶子节点
-			currentNode.isLeaf = currentNode.children == nil && currentNode.paramChild == nil && currentNode.anyChild == nil
+			currentNode.isLeaf = currentNode.children == nil && currentNode.paramChildren == nil && currentNode.anyChild == nil
 		} else
 		// 当lcpLen(公共部分长度) 小于 search 长度
 		// https://i.miji.bid/2023/11/26/695dcac0259f92521a317b3f94f53fc5.png
@@ -296,55 +400,261 @@ func (r *RadixTree) insert(path string, h server.HandlersChain, t kind, ppath st
 			// search 切去公共部分
 			search = search[lcpLen:]
 			// 查看currentNode所指节点 有没有以search[0]字符开头的子节点
-			c := currentNode.findChildWithLabel(search[0])
+			// : 参数节点还需要约束相同
+			var constraint *paramConstraint
+			if search[0] == paramLabel {
+				constraint = constraints[params]
+			}
+			c := currentNode.findChildWithLabel(search[0], constraint)
 			if c != nil {
-				// 更新currentNode指针的指向(指针指向查询到的子节点)
+				// 更新currentNode指针的指向(指针指向查询到的子节点的副本)
 				// 继续循环
-				currentNode = c
+				currentNode = currentNode.copyChild(c)
+				if c.kind == pkind {
+					params++
+				}
 				continue
 			}
 
 			// 如果没有以search[0]字符开头的子节点 则创建子节点保存此次插入的参数
-			n := newNode(t, search, currentNode, nil, h, ppath, pnames, nil, nil)
+			n := newNode(t, search, nil, h, ppath, pnames, nil, nil)
+			n.constraint = constraint
+			n.middlewares = middlewares
 			// 根据类型将创建的子节点 和 currentNode所指节点 形成关系(append)
 			switch t {
 			case skind:
 				currentNode.children = append(currentNode.children, n)
 			case pkind:
-				currentNode.paramChild = n
+				currentNode.addParamChild(n)
 			case akind:
 				currentNode.anyChild = n
 			}
-			currentNode.isLeaf = currentNode.children == nil && currentNode.paramChild == nil && currentNode.anyChild == nil
+			currentNode.isLeaf = currentNode.children == nil && currentNode.paramChildren == nil && currentNode.anyChild == nil
 		} else {
 			if currentNode.handlers != nil && h != nil {
-				panic("handlers are already registered for path '" + ppath + "'")
+				return newRouteError(ErrDuplicateRoute, ppath, currentNode.ppath, "handlers are already registered for path '"+ppath+"'")
 			}
 
 			if h != nil {
 				currentNode.handlers = h
 				currentNode.ppath = ppath
 				currentNode.pnames = pnames
+				currentNode.middlewares = middlewares
 			}
 		}
+		return nil
+	}
+
+}
+
+// copy 浅拷贝 n，子节点与 n 共享，但 children 和 paramChildren 单独复制，修改副本的子节点列表不影响 n
+func (n *node) copy() *node {
+	cp := *n
+	cp.children = n.children.copy()
+	cp.paramChildren = n.paramChildren.copy()
+	return &cp
+}
+
+// copy 复制子节点列表，nil 仍然返回 nil
+func (c children) copy() children {
+	if c == nil {
+		return nil
+	}
+	cp := make(children, len(c))
+	copy(cp, c)
+	return cp
+}
+
+// copyChild 复制 n 的子节点 c，并在 n 中以副本替换 c，n 必须是本次修改中复制出的节点
+func (n *node) copyChild(c *node) *node {
+	cp := c.copy()
+	n.setChild(c, cp)
+	return cp
+}
+
+// setChild 将 n 的子节点 old 替换为 c
+func (n *node) setChild(old, c *node) {
+	if old == n.anyChild {
+		n.anyChild = c
 		return
 	}
+	if !n.children.set(old, c) {
+		n.paramChildren.set(old, c)
+	}
+}
+
+// set 将列表中的 old 替换为 c，old 不在列表中时返回 false
+func (ch children) set(old, c *node) bool {
+	for i, child := range ch {
+		if child == old {
+			ch[i] = c
+			return true
+		}
+	}
+	return false
+}
+
+// addParamChild 添加 : 参数节点，有约束的放在没有约束的之前
+func (n *node) addParamChild(c *node) {
+	n.paramChildren = append(n.paramChildren, c)
+	if last := len(n.paramChildren) - 1; c.constraint != nil && last > 0 && n.paramChildren[last-1].constraint == nil {
+		n.paramChildren[last-1], n.paramChildren[last] = c, n.paramChildren[last-1]
+	}
+}
+
+// allChildren 按照 static > param > any 的顺序返回 n 的所有子节点
+func (n *node) allChildren() []*node {
+	all := append([]*node(nil), n.children...)
+	all = append(all, n.paramChildren...)
+	if n.anyChild != nil {
+		all = append(all, n.anyChild)
+	}
+	return all
+}
+
+// --------------------------------------------------------------------------------------------------------
+
+// replace 保留以 ppath 注册的路由中注册时所在路由组的中间件，将之后的 handlers 替换为 h
+// 路由不存在时返回 ErrRouteNotFound，组合后 handler 过多时返回 ErrTooManyHandlers
+func (r *RadixTree) replace(ppath string, h server.HandlersChain) error {
+	root, err := r.root.replace(ppath, h)
+	if err != nil {
+		return err
+	}
+	if root == r.root {
+		return ErrRouteNotFound
+	}
+	r.root = root
+	return nil
+}
+
+// replace 返回替换 n 的子树中以 ppath 注册的路由后 n 的副本
+// 只复制被修改的节点及其祖先，子树中不存在该路由时返回 n 本身
+func (n *node) replace(ppath string, h server.HandlersChain) (*node, error) {
+	cp := n
+	if n.handlers != nil && n.ppath == ppath {
+		if n.middlewares+len(h) >= 63 {
+			return nil, newRouteError(ErrTooManyHandlers, ppath, nilString, "too many handlers")
+		}
+		cp = n.copy()
+		cp.handlers = make(server.HandlersChain, n.middlewares+len(h))
+		copy(cp.handlers, n.handlers[:n.middlewares])
+		copy(cp.handlers[n.middlewares:], h)
+	}
+	for _, child := range n.allChildren() {
+		c, err := child.replace(ppath, h)
+		if err != nil {
+			return nil, err
+		}
+		if c != child {
+			if cp == n {
+				cp = n.copy()
+			}
+			cp.setChild(child, c)
+		}
+	}
+	return cp, nil
+}
 
+// remove 删除以 ppath 注册的路由，路由不存在时返回 false
+func (r *RadixTree) remove(ppath string) bool {
+	root := r.root.remove(ppath)
+	if root == r.root {
+		return false
+	}
+	// 路由全部删除后 重置根节点
+	if root.handlers == nil && root.isLeaf {
+		root = &node{}
+	}
+	r.root = root
+	return true
 }
 
-func newNode(t kind, pre string, p *node, child children, mh server.HandlersChain, ppath string, pnames []string, paramChildren, anyChildren *node) *node {
+// remove 返回删除 n 的子树中以 ppath 注册的路由后 n 的副本
+// 删除后清理不再需要的子节点，并将只剩一个静态子节点的静态节点与子节点合并
+// 只复制被修改的节点及其祖先，子树中不存在该路由时返回 n 本身
+// 可选参数路由会对应多个节点，因此遍历整棵子树
+func (n *node) remove(ppath string) *node {
+	cp := n
+	if n.handlers != nil && n.ppath == ppath {
+		cp = n.copy()
+		cp.handlers = nil
+		cp.ppath = nilString
+		cp.pnames = nil
+		cp.middlewares = 0
+	}
+	for _, child := range n.allChildren() {
+		c := child.remove(ppath)
+		if c == child {
+			continue
+		}
+		if cp == n {
+			cp = n.copy()
+		}
+		// 没有 handlers 的叶子节点从父节点上摘除
+		if c.handlers == nil && c.isLeaf {
+			c = nil
+		}
+		cp.setChild(child, c)
+	}
+	if cp != n {
+		cp.children = cp.children.compact()
+		cp.paramChildren = cp.paramChildren.compact()
+		cp.isLeaf = cp.children == nil && cp.paramChildren == nil && cp.anyChild == nil
+		cp.merge()
+	}
+	return cp
+}
+
+// compact 去掉列表中被摘除的子节点，全部摘除时返回 nil
+func (ch children) compact() children {
+	i := 0
+	for _, child := range ch {
+		if child != nil {
+			ch[i] = child
+			i++
+		}
+	}
+	if i == 0 {
+		return nil
+	}
+	return ch[:i]
+}
+
+// merge 当 n 是没有 handlers 的静态节点，并且只有一个静态子节点时，将子节点合并到 n
+// eg: /us 节点只剩下 er 子节点时 合并为 /user
+func (n *node) merge() {
+	if n.kind != skind || n.handlers != nil || len(n.children) != 1 || n.paramChildren != nil || n.anyChild != nil {
+		return
+	}
+	child := n.children[0]
+	if child.kind != skind {
+		return
+	}
+	n.prefix += child.prefix
+	n.label = n.prefix[0]
+	n.handlers = child.handlers
+	n.ppath = child.ppath
+	n.pnames = child.pnames
+	n.middlewares = child.middlewares
+	n.children = child.children
+	n.paramChildren = child.paramChildren
+	n.anyChild = child.anyChild
+	n.isLeaf = child.isLeaf
+}
+
+func newNode(t kind, pre string, child children, mh server.HandlersChain, ppath string, pnames []string, paramChildren children, anyChildren *node) *node {
 	return &node{
-		kind:       t,
-		label:      pre[0],
-		prefix:     pre,
-		parent:     p,
-		children:   child,
-		ppath:      ppath,
-		pnames:     pnames,
-		handlers:   mh,
-		paramChild: paramChildren,
-		anyChild:   anyChildren,
-		isLeaf:     child == nil && paramChildren == nil && anyChildren == nil,
+		kind:          t,
+		label:         pre[0],
+		prefix:        pre,
+		children:      child,
+		ppath:         ppath,
+		pnames:        pnames,
+		handlers:      mh,
+		paramChildren: paramChildren,
+		anyChild:      anyChildren,
+		isLeaf:        child == nil && paramChildren == nil && anyChildren == nil,
 	}
 }
 
@@ -364,13 +674,21 @@ func (r *RadixTree) find(path string, paramsPointer *server.Params, unescape boo
 		searchIndex = 0      // search 上的索引 可变
 		buf         []byte
 		paramIndex  int // 表示 paramsPointer中已有的参数个数 可变
+		// ancestors cn 的所有祖先节点 回溯时从中取出父节点
+		ancestors = make([]*node, 0, 16)
+		// nextParam 回溯到父节点后 下一个要尝试的 : 参数节点在 paramChildren 中的下标
+		nextParam int
 	)
 
 	// region ========== 回溯：向上退出一层、根据类型优先级找到下个节点、重置状态 指针 方便继续搜索 ==========
 
 	backtrackToNextNodeKind := func(fromKind kind) (nextNodeKind kind, vaild bool) {
-		previous := cn       // previous 和 cn 指向同一个节点设为X
-		cn = previous.parent // cn 更新指向 X的父节点
+		previous := cn // previous 和 cn 指向同一个节点设为X
+		cn = nil       // cn 更新指向 X的父节点 X为根节点时为nil
+		if n := len(ancestors); n > 0 {
+			cn = ancestors[n-1]
+			ancestors = ancestors[:n-1]
+		}
 		// valid 表示是否回退回溯成功
 		vaild = cn != nil
 
@@ -383,6 +701,16 @@ func (r *RadixTree) find(path string, paramsPointer *server.Params, unescape boo
 		} else {
 			nextNodeKind = previous.kind + 1
 		}
+		// 参数节点之后,同一位置还有其他 : 参数节点时先尝试它们
+		nextParam = 0
+		if previous.kind == pkind && cn != nil {
+			for i, child := range cn.paramChildren {
+				if child == previous && i+1 < len(cn.paramChildren) {
+					nextNodeKind, nextParam = pkind, i+1
+					break
+				}
+			}
+		}
 		if fromKind == skind {
 			return
 		}
@@ -397,6 +725,7 @@ func (r *RadixTree) find(path string, paramsPointer *server.Params, unescape boo
 			paramIndex--
 			// 对于:或者*节点 , prefix的值是: 或者 *, 因此无法从prefix中知道searchIndex应该向前回溯多少。
 			// 但实际的参数值被保存到参数列表中，根据参数索引获得参数值。参数值就是searchIndex应该向前回溯的个数。
+			// 参数值在查找结束后才反转义，因此长度与 path 中一致
 			searchIndex -= len((*paramsPointer)[paramIndex].Value)
 			// 从paramsPointer中删除最后一个参数
 			(*paramsPointer) = (*paramsPointer)[:paramIndex]
@@ -408,6 +737,7 @@ func (r *RadixTree) find(path string, paramsPointer *server.Params, unescape boo
 	// endregion
 
 	// region ========== 顺序: static > param > any ==========
+Walk:
 	for {
 		// region ========== 静态 ==========
 
@@ -498,6 +828,7 @@ func (r *RadixTree) find(path string, paramsPointer *server.Params, unescape boo
 			// 检查cn指向的节点是否有search[0]字符开头的子节点
 			if child := cn.findChild(search[0]); child != nil {
 				// cn指针更新 指向以search[0]字符开头的子节点
+				ancestors = append(ancestors, cn)
 				cn = child
 				// 继续查找 切search
 				continue
@@ -524,64 +855,65 @@ func (r *RadixTree) find(path string, paramsPointer *server.Params, unescape boo
 
 		// region ========== Param ==========
 	Param:
-		// cn所指节点的paramChild不为nil 并且 search会被切割的字符串!=""。这时说明 cn所指节点是Param节点
-		if child := cn.paramChild; search != nilString && child != nil {
-			cn = child
+		// cn所指节点有 : 参数子节点 并且 search会被切割的字符串!=""。按照 paramChildren 的顺序依次尝试
+		if children := cn.paramChildren[nextParam:]; search != nilString && len(children) > 0 {
+			nextParam = 0
 			// i指向search中 / 字符所在的下标，也就是参数值结束位置。
-			i := strings.Index(search, slash)
-			if i == -1 {
+			end := strings.Index(search, slash)
+			if end == -1 {
 				// 说明search 中没有 / 字符
 				// i指向search最后尾部
-				i = len(search)
+				end = len(search)
 			}
-			// 参数列表容量扩大1个= 表示参数列表中已有的参数个数 + 扩1
-			(*paramsPointer) = (*paramsPointer)[:(paramIndex + 1)]
-			// 取出param的值
-			val := search[:i]
-			// 如果需要对参数值进行反转义, 则执行反转义操作
-			if unescape {
-				if v, err := url.QueryUnescape(search[:i]); err == nil {
-					val = v
+			for _, child := range children {
+				i := end
+				// 参数后面紧跟分隔符时 eg: /files/:name.:ext
+				// 参数值到路径段中第一个分隔符为止 (最短匹配)
+				if d := child.delimiterIndex(search[:i]); d != -1 {
+					i = d
 				}
-			}
-			// 将param值添加到参数列表
-			(*paramsPointer)[paramIndex].Value = val
-			// 现在已经向参数列表添加1个参数
-			// paramIndex++表示参数列表中已有的参数个数 + 1
-			paramIndex++
-
-			// search切去/字符之前部分
-			search = search[i:]
-			searchIndex = searchIndex + i
-
-			// search切完了
-			if search == nilString {
-				//	如果cn指向的节点有以 / 开头的子节点，同时 子节点有handlers，重定向
-				if cd := cn.findChild('/'); cd != nil && (cd.handlers != nil || cd.anyChild != nil) {
-					res.tsr = true
+				// 参数值满足约束时才进入参数节点，否则继续尝试下一个参数节点和任意节点
+				if child.constraint != nil && !child.constraint.match(unescapeValue(search[:i], unescape)) {
+					continue
 				}
+				ancestors = append(ancestors, cn)
+				cn = child
+				// 参数列表容量扩大1个= 表示参数列表中已有的参数个数 + 扩1
+				(*paramsPointer) = (*paramsPointer)[:(paramIndex + 1)]
+				// 将param值添加到参数列表 反转义在查找结束后进行
+				(*paramsPointer)[paramIndex].Value = search[:i]
+				// 现在已经向参数列表添加1个参数
+				// paramIndex++表示参数列表中已有的参数个数 + 1
+				paramIndex++
+
+				// search切去/字符之前部分
+				search = search[i:]
+				searchIndex = searchIndex + i
+
+				// search切完了
+				if search == nilString {
+					//	如果cn指向的节点有以 / 开头的子节点，同时 子节点有handlers，重定向
+					if cd := cn.findChild('/'); cd != nil && (cd.handlers != nil || cd.anyChild != nil) {
+						res.tsr = true
+					}
+				}
+				continue Walk
 			}
-			continue
 		}
+		nextParam = 0
 		// endregion
 
 		// region ========== Any ==========
 	Any:
 		// cn所指节点的anyChild不为nil
 		if child := cn.anyChild; child != nil {
+			ancestors = append(ancestors, cn)
 			cn = child
 			// 参数列表容量扩大1个= 表示参数列表中已有的参数个数 + 扩1
 			(*paramsPointer) = (*paramsPointer)[:(paramIndex + 1)]
 			index := len(cn.pnames) - 1
-			val := search
-			// 如果需要对参数值进行反转义, 则执行反转义操作
-			if unescape {
-				if v, err := url.QueryUnescape(search); err == nil {
-					val = v
-				}
-			}
-			// 将param值添加到参数列表
-			(*paramsPointer)[index].Value = bytesconv.B2s(append(buf, val...))
+			// 将param值添加到参数列表 反转义在查找结束后进行
+			(*paramsPointer)[index].Value = bytesconv.B2s(append(buf, search...))
 			// 更新索引 以备在找不到匹配的处理程序时进行回溯
 			paramIndex++
 			searchIndex += len(search)
@@ -616,12 +948,24 @@ func (r *RadixTree) find(path string, paramsPointer *server.Params, unescape boo
 		res.fullPath = cn.ppath
 		for i, name := range cn.pnames {
 			(*paramsPointer)[i].Key = name
+			// 如果需要对参数值进行反转义, 则执行反转义操作
+			(*paramsPointer)[i].Value = unescapeValue((*paramsPointer)[i].Value, unescape)
 		}
 	}
 	// endregion
 	return
 }
 
+// unescapeValue unescape 为 true 时对参数值进行反转义，反转义失败时返回原值
+func unescapeValue(value string, unescape bool) string {
+	if unescape {
+		if v, err := url.PathUnescape(value); err == nil {
+			return v
+		}
+	}
+	return value
+}
+
 // findChild 查找子节点中，是否存在前缀以 参数 开头的子节点
 func (n *node) findChild(l byte) *node {
 	for _, c := range n.children {
@@ -631,17 +975,160 @@ func (n *node) findChild(l byte) *node {
 	}
 	return nil
 }
-func (n *node) findChildWithLabel(l byte) *node {
+
+// findChildWithLabel 查找以 l 开头的子节点，: 参数节点还需要约束与 constraint 相同
+func (n *node) findChildWithLabel(l byte, constraint *paramConstraint) *node {
 	for _, c := range n.children {
 		if c.label == l {
 			return c
 		}
 	}
 	if l == paramLabel {
-		return n.paramChild
+		for _, c := range n.paramChildren {
+			if c.constraint.constraintExpr() == constraint.constraintExpr() {
+				return c
+			}
+		}
+		return nil
 	}
 	if l == anyLabel {
 		return n.anyChild
 	}
 	return nil
 }
+
+// delimiterIndex 返回参数值 seg 中第一个分隔符的位置，不存在时返回 -1
+// 分隔符是 : 参数节点的静态子节点中除 / 以外的首字母 eg: /files/:name.:ext 中的 .
+// 参数值不能为空，所以从 seg[1] 开始查找
+func (n *node) delimiterIndex(seg string) int {
+	for i := 1; i < len(seg); i++ {
+		for _, child := range n.children {
+			if child.label != '/' && child.label == seg[i] {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// --------------------------------------------------------------------------------------------------------
+
+// findCaseInsensitivePath 不区分大小写地查找 path，返回注册时的规范路径
+// 参数值保持请求中的原样，只有静态部分被修正为注册时的大小写
+// fixTrailingSlash 为 true 时，尾部斜杠不同的路径同样视为匹配，并修正尾部斜杠
+func (r *RadixTree) findCaseInsensitivePath(path string, fixTrailingSlash bool) (string, bool) {
+	if r.root == nil || path == nilString {
+		return nilString, false
+	}
+	buf, found := r.root.findCaseInsensitivePath(path, make([]byte, 0, len(path)+1), 0, fixTrailingSlash)
+	return string(buf), found
+}
+
+// findCaseInsensitivePath 以 n 为起点递归查找，查找顺序与 find 一致: static > param > any
+// 匹配的部分追加到 buf 后返回
+// 节点的 prefix 可能在多字节字符中间被分裂，不完整的字符无法比较大小写，需要留到子节点补全后再比较
+// pend 表示 buf 末尾尚未比较的字节数，path 从这些字节对应的位置开始
+func (n *node) findCaseInsensitivePath(path string, buf []byte, pend int, fixTrailingSlash bool) ([]byte, bool) {
+	switch n.kind {
+	case skind:
+		// 加上上一个节点未比较的字节
+		canonical := string(buf[len(buf)-pend:]) + n.prefix
+		if len(path) < len(canonical) {
+			// region ========== 重定向 ==========
+
+			// path 比 canonical 只少一个尾部斜杠 eg: path 是 /DOC  n.prefix 是 /doc/
+			if fixTrailingSlash &&
+				len(path)+1 == len(canonical) &&
+				canonical[len(path)] == '/' &&
+				strings.EqualFold(path, canonical[:len(path)]) &&
+				n.handlers != nil {
+				return append(buf, n.prefix...), true
+			}
+
+			// endregion
+			return buf, false
+		}
+		// 只比较完整的字符
+		cut := lastRuneBoundary(canonical)
+		if !strings.EqualFold(path[:cut], canonical[:cut]) {
+			return buf, false
+		}
+		buf = append(buf, n.prefix...)
+		path = path[cut:]
+		pend = len(canonical) - cut
+	case pkind:
+		// 参数值到下一个 / 或第一个分隔符为止，并且不能为空
+		i := strings.Index(path, slash)
+		if i == -1 {
+			i = len(path)
+		}
+		if d := n.delimiterIndex(path[:i]); d != -1 {
+			i = d
+		}
+		if i == 0 || (n.constraint != nil && !n.constraint.match(path[:i])) {
+			return buf, false
+		}
+		buf = append(buf, path[:i]...)
+		path = path[i:]
+	case akind:
+		// 任意节点匹配剩余全部内容
+		return append(buf, path...), n.handlers != nil
+	}
+
+	// path 切完了
+	if path == nilString {
+		if n.handlers != nil {
+			return buf, true
+		}
+		if n.anyChild != nil && n.anyChild.handlers != nil {
+			return buf, true
+		}
+		// 加上尾部斜杠后存在路由
+		if fixTrailingSlash {
+			if cd := n.findChild('/'); cd != nil && cd.prefix == slash && cd.handlers != nil {
+				return append(buf, '/'), true
+			}
+		}
+		return buf, false
+	}
+
+	// 静态子节点的首字母可能大小写不同，因此需要逐个尝试
+	for _, child := range n.children {
+		if out, ok := child.findCaseInsensitivePath(path, buf, pend, fixTrailingSlash); ok {
+			return out, true
+		}
+	}
+	// 存在未比较的字节时 只能由静态子节点补全
+	if pend != 0 {
+		return buf, false
+	}
+	for _, child := range n.paramChildren {
+		if out, ok := child.findCaseInsensitivePath(path, buf, 0, fixTrailingSlash); ok {
+			return out, true
+		}
+	}
+	if n.anyChild != nil {
+		if out, ok := n.anyChild.findCaseInsensitivePath(path, buf, 0, fixTrailingSlash); ok {
+			return out, true
+		}
+	}
+
+	// 去掉尾部斜杠后存在路由
+	if fixTrailingSlash && path == slash && n.handlers != nil {
+		return buf, true
+	}
+	return buf, false
+}
+
+// lastRuneBoundary 返回 s 中最后一个完整 UTF-8 字符的结束位置
+func lastRuneBoundary(s string) int {
+	for i := len(s) - 1; i >= 0 && i >= len(s)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(s[i]) {
+			if utf8.FullRuneInString(s[i:]) {
+				return len(s)
+			}
+			return i
+		}
+	}
+	return len(s)
+}
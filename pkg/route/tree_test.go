@@ -2,9 +2,11 @@ package route
 
 import (
 	"context"
+	"errors"
+	server2 "github.com/Yuki-J1/wailsrouter/pkg/app/server"
+	"github.com/cloudwego/hertz/pkg/common/test/assert"
 	"strings"
 	"testing"
-	server2 "wailsrouter/pkg/app/server"
 )
 
 var fakeHandlerValue string
@@ -466,3 +468,614 @@ func TestTreeParamNotOptimize(t *testing.T) {
 		{"/1", false, "/:paramb", server2.Params{server2.Param{Key: "paramb", Value: "1"}}},             // 查到
 	})
 }
+
+// 不区分大小写查找 测试
+func TestTreeFindCaseInsensitivePath(t *testing.T) {
+	tree := &RadixTree{root: &node{}}
+
+	routes := [...]string{
+		"/hi",
+		"/b/",
+		"/ABC/",
+		"/search/:query",
+		"/cmd/:tool/",
+		"/src/*filepath",
+		"/x",
+		"/x/y",
+		"/y/",
+		"/y/z",
+		"/0/:id",
+		"/0/:id/1",
+		"/1/:id/",
+		"/1/:id/2",
+		"/aa",
+		"/a/",
+		"/doc",
+		"/doc/go_faq.html",
+		"/doc/go1.html",
+		"/doc/go/away",
+		"/no/a",
+		"/no/b",
+		"/Π",
+		"/u/apfêl/",
+		"/u/äpfêl/",
+		"/u/öpfêl",
+		"/v/Äpfêl/",
+		"/v/Öpfêl",
+		"/w/♬",
+		"/w/♭/",
+		"/w/𠜎",
+		"/w/𠜏/",
+	}
+	for _, route := range routes {
+		recv := catchPanic(func() {
+			tree.addRoute(route, fakeHandler(route))
+		})
+		if recv != nil {
+			t.Fatalf("panic inserting route '%s': %v", route, recv)
+		}
+	}
+
+	// 不修正尾部斜杠时 注册的路由规则本身都应该能找到
+	for _, route := range routes {
+		out, found := tree.findCaseInsensitivePath(route, false)
+		if !found {
+			t.Errorf("Route '%s' not found!", route)
+		} else if out != route {
+			t.Errorf("Wrong result for route '%s': %s", route, out)
+		}
+	}
+
+	tests := []struct {
+		in    string
+		out   string
+		found bool
+		slash bool
+	}{
+		{"/HI", "/hi", true, false},
+		{"/HI/", "/hi", true, true},
+		{"/B", "/b/", true, true},
+		{"/B/", "/b/", true, false},
+		{"/abc", "/ABC/", true, true},
+		{"/abc/", "/ABC/", true, false},
+		{"/aBc", "/ABC/", true, true},
+		{"/aBc/", "/ABC/", true, false},
+		{"/abC", "/ABC/", true, true},
+		{"/abC/", "/ABC/", true, false},
+		{"/SEARCH/QUERY", "/search/QUERY", true, false},
+		{"/SEARCH/QUERY/", "/search/QUERY", true, true},
+		{"/CMD/TOOL/", "/cmd/TOOL/", true, false},
+		{"/CMD/TOOL", "/cmd/TOOL/", true, true},
+		{"/SRC/FILE/PATH", "/src/FILE/PATH", true, false},
+		{"/x/Y", "/x/y", true, false},
+		{"/x/Y/", "/x/y", true, true},
+		{"/X/y", "/x/y", true, false},
+		{"/X/y/", "/x/y", true, true},
+		{"/X/Y", "/x/y", true, false},
+		{"/X/Y/", "/x/y", true, true},
+		{"/Y/", "/y/", true, false},
+		{"/Y", "/y/", true, true},
+		{"/Y/z", "/y/z", true, false},
+		{"/Y/z/", "/y/z", true, true},
+		{"/Y/Z", "/y/z", true, false},
+		{"/Y/Z/", "/y/z", true, true},
+		{"/y/Z", "/y/z", true, false},
+		{"/y/Z/", "/y/z", true, true},
+		{"/Aa", "/aa", true, false},
+		{"/Aa/", "/aa", true, true},
+		{"/AA", "/aa", true, false},
+		{"/AA/", "/aa", true, true},
+		{"/aA", "/aa", true, false},
+		{"/aA/", "/aa", true, true},
+		{"/A/", "/a/", true, false},
+		{"/A", "/a/", true, true},
+		{"/DOC", "/doc", true, false},
+		{"/DOC/", "/doc", true, true},
+		{"/NO", "", false, true},
+		{"/DOC/GO", "", false, true},
+		{"/π", "/Π", true, false},
+		{"/π/", "/Π", true, true},
+		{"/u/ÄPFÊL/", "/u/äpfêl/", true, false},
+		{"/u/ÄPFÊL", "/u/äpfêl/", true, true},
+		{"/u/ÖPFÊL/", "/u/öpfêl", true, true},
+		{"/u/ÖPFÊL", "/u/öpfêl", true, false},
+		{"/v/äpfêL/", "/v/Äpfêl/", true, false},
+		{"/v/äpfêL", "/v/Äpfêl/", true, true},
+		{"/v/öpfêL/", "/v/Öpfêl", true, true},
+		{"/v/öpfêL", "/v/Öpfêl", true, false},
+		{"/w/♬/", "/w/♬", true, true},
+		{"/w/♭", "/w/♭/", true, true},
+		{"/w/𠜎/", "/w/𠜎", true, true},
+		{"/w/𠜏", "/w/𠜏/", true, true},
+	}
+	// 修正尾部斜杠
+	for _, test := range tests {
+		out, found := tree.findCaseInsensitivePath(test.in, true)
+		if found != test.found || (found && (out != test.out)) {
+			t.Errorf("Wrong result for '%s': got %s, %t; want %s, %t",
+				test.in, out, found, test.out, test.found)
+		}
+	}
+	// 不修正尾部斜杠
+	for _, test := range tests {
+		out, found := tree.findCaseInsensitivePath(test.in, false)
+		if test.slash {
+			if found {
+				t.Errorf("Found without fixTrailingSlash: %s; got %s", test.in, out)
+			}
+		} else if !found || out != test.out {
+			t.Errorf("Wrong result for '%s': got %s, %t; want %s, %t",
+				test.in, out, found, test.out, test.found)
+		}
+	}
+}
+
+// 参数值反转义 测试
+func TestTreeUnescapeParams(t *testing.T) {
+	tree := &RadixTree{root: &node{}}
+
+	routes := [...]string{
+		"/",
+		"/cmd/:tool/:sub",
+		"/cmd/:tool/",
+		"/src/*filepath",
+		"/search/:query",
+		"/files/:dir/*filepath",
+		"/info/:user/project/:project",
+		"/info/:user",
+	}
+	for _, route := range routes {
+		tree.addRoute(route, fakeHandler(route))
+	}
+
+	unescape := true
+	checkRequests(t, tree, testRequests{
+		{"/", false, "/", nil},
+		{"/cmd/test/", false, "/cmd/:tool/", server2.Params{server2.Param{Key: "tool", Value: "test"}}},
+		{"/cmd/test", true, "", nil},
+		{"/src/some/file.png", false, "/src/*filepath", server2.Params{server2.Param{Key: "filepath", Value: "some/file.png"}}},
+		{"/src/some/file+test.png", false, "/src/*filepath", server2.Params{server2.Param{Key: "filepath", Value: "some/file+test.png"}}},
+		{"/src/some/file++++%%%%test.png", false, "/src/*filepath", server2.Params{server2.Param{Key: "filepath", Value: "some/file++++%%%%test.png"}}},
+		{"/src/some/file%2Ftest.png", false, "/src/*filepath", server2.Params{server2.Param{Key: "filepath", Value: "some/file/test.png"}}},
+		{"/search/someth!ng+in+ünìcodé", false, "/search/:query", server2.Params{server2.Param{Key: "query", Value: "someth!ng+in+ünìcodé"}}},
+		{"/info/gordon/project/go", false, "/info/:user/project/:project", server2.Params{server2.Param{Key: "user", Value: "gordon"}, server2.Param{Key: "project", Value: "go"}}},
+		{"/info/slash%2Fgordon", false, "/info/:user", server2.Params{server2.Param{Key: "user", Value: "slash/gordon"}}},
+		{"/info/slash%2Fgordon/project/Project%20%231", false, "/info/:user/project/:project", server2.Params{server2.Param{Key: "user", Value: "slash/gordon"}, server2.Param{Key: "project", Value: "Project #1"}}},
+		{"/info/slash%%%%", false, "/info/:user", server2.Params{server2.Param{Key: "user", Value: "slash%%%%"}}},
+		{"/info/slash%%%%2Fgordon/project/Project%%%%20%231", false, "/info/:user/project/:project", server2.Params{server2.Param{Key: "user", Value: "slash%%%%2Fgordon"}, server2.Param{Key: "project", Value: "Project%%%%20%231"}}},
+	}, unescape)
+}
+
+// checkNodes 检查每个节点的 isLeaf 是否正确，并且除根节点外不存在没有 handlers 的叶子节点
+func checkNodes(t *testing.T, root *node) {
+	var check func(n *node)
+	check = func(n *node) {
+		isLeaf := n.children == nil && n.paramChildren == nil && n.anyChild == nil
+		if n.isLeaf != isLeaf {
+			t.Errorf("isLeaf mismatch for node '%s': %t", n.prefix, n.isLeaf)
+		}
+		if n != root && isLeaf && n.handlers == nil {
+			t.Errorf("leaf node '%s' without handlers", n.prefix)
+		}
+		for _, child := range n.allChildren() {
+			check(child)
+		}
+	}
+	check(root)
+}
+
+// 删除路由 测试
+func TestTreeRemove(t *testing.T) {
+	tree := &RadixTree{root: &node{}}
+
+	routes := [...]string{
+		"/",
+		"/hi",
+		"/contact",
+		"/co",
+		"/cmd/:tool/:sub",
+		"/cmd/:tool/",
+		"/src/*filepath",
+		"/search/",
+		"/search/:query",
+		"/user_:name",
+		"/user_:name/about",
+		"/files/:dir/*filepath",
+	}
+	for _, route := range routes {
+		tree.addRoute(route, fakeHandler(route))
+	}
+
+	removed := [...]string{
+		"/co",
+		"/cmd/:tool/:sub",
+		"/search/:query",
+		"/user_:name",
+		"/files/:dir/*filepath",
+	}
+	for _, route := range removed {
+		if !tree.remove(route) {
+			t.Errorf("route '%s' not removed", route)
+		}
+		if tree.remove(route) {
+			t.Errorf("route '%s' removed twice", route)
+		}
+		checkNodes(t, tree.root)
+	}
+
+	checkRequests(t, tree, testRequests{
+		{"/", false, "/", nil},
+		{"/hi", false, "/hi", nil},
+		{"/contact", false, "/contact", nil},
+		{"/co", true, "", nil},
+		{"/cmd/test/", false, "/cmd/:tool/", server2.Params{server2.Param{Key: "tool", Value: "test"}}},
+		{"/cmd/test/3", true, "", nil},
+		{"/src/some/file.png", false, "/src/*filepath", server2.Params{server2.Param{Key: "filepath", Value: "some/file.png"}}},
+		{"/search/", false, "/search/", nil},
+		{"/search/someth!ng", true, "", nil},
+		{"/user_gopher", true, "", nil},
+		{"/user_gopher/about", false, "/user_:name/about", server2.Params{server2.Param{Key: "name", Value: "gopher"}}},
+		{"/files/js/inc/framework.js", true, "", nil},
+	})
+
+	// 被删除的路由可以重新注册
+	tree.addRoute("/search/:query", fakeHandler("/search/:query"))
+	checkRequests(t, tree, testRequests{
+		{"/search/someth!ng", false, "/search/:query", server2.Params{server2.Param{Key: "query", Value: "someth!ng"}}},
+	})
+
+	// 只剩一个静态子节点时合并
+	tree = &RadixTree{root: &node{}}
+	tree.addRoute("/user", fakeHandler("/user"))
+	tree.addRoute("/us", fakeHandler("/us"))
+	tree.remove("/us")
+	checkNodes(t, tree.root)
+	if tree.root.prefix != "/user" || !tree.root.isLeaf {
+		t.Errorf("expected merged root '/user', got '%s'", tree.root.prefix)
+	}
+
+	// 全部删除后根节点被重置
+	tree.remove("/user")
+	if tree.root.prefix != nilString || tree.root.handlers != nil {
+		t.Errorf("expected empty root, got '%s'", tree.root.prefix)
+	}
+	tree.addRoute("/a", fakeHandler("/a"))
+	checkRequests(t, tree, testRequests{
+		{"/a", false, "/a", nil},
+		{"/user", true, "", nil},
+	})
+}
+
+// 替换路由 测试
+func TestTreeReplace(t *testing.T) {
+	tree := &RadixTree{root: &node{}}
+	tree.addRoute("/search/:query", fakeHandler("/search/:query"))
+
+	if tree.replace("/search/:q", fakeHandler("replaced")) != ErrRouteNotFound {
+		t.Errorf("replaced unregistered route")
+	}
+	if tree.replace("/search/:query", fakeHandler("replaced")) != nil {
+		t.Errorf("route not replaced")
+	}
+	checkRequests(t, tree, testRequests{
+		{"/search/go", false, "replaced", server2.Params{server2.Param{Key: "query", Value: "go"}}},
+	})
+}
+
+// 修改路由树不影响之前的快照 测试
+func TestTreeCopyOnWrite(t *testing.T) {
+	tree := &RadixTree{root: &node{}}
+	routes := [...]string{
+		"/user/:name",
+		"/user/:name/profile",
+		"/src/*filepath",
+		"/hi",
+	}
+	for _, route := range routes {
+		tree.addRoute(route, fakeHandler(route))
+	}
+	requests := testRequests{
+		{"/user/gopher", false, "/user/:name", server2.Params{server2.Param{Key: "name", Value: "gopher"}}},
+		{"/user/gopher/profile", false, "/user/:name/profile", server2.Params{server2.Param{Key: "name", Value: "gopher"}}},
+		{"/src/some/file.png", false, "/src/*filepath", server2.Params{server2.Param{Key: "filepath", Value: "some/file.png"}}},
+		{"/hi", false, "/hi", nil},
+		{"/users", true, "", nil},
+		{"/user/gopher/posts", true, "", nil},
+	}
+
+	// snapshot 与 tree 共享节点
+	snapshot := &RadixTree{root: tree.root}
+	// 分裂 /user/ 和 /hi 所在的节点
+	tree.addRoute("/users", fakeHandler("/users"))
+	tree.addRoute("/h", fakeHandler("/h"))
+	tree.addRoute("/user/:name/posts", fakeHandler("/user/:name/posts"))
+	if tree.replace("/user/:name", fakeHandler("replaced")) != nil {
+		t.Errorf("route not replaced")
+	}
+	if !tree.remove("/src/*filepath") {
+		t.Errorf("route not removed")
+	}
+	checkNodes(t, tree.root)
+	checkNodes(t, snapshot.root)
+
+	checkRequests(t, snapshot, requests)
+	checkRequests(t, tree, testRequests{
+		{"/user/gopher", false, "replaced", server2.Params{server2.Param{Key: "name", Value: "gopher"}}},
+		{"/user/gopher/posts", false, "/user/:name/posts", server2.Params{server2.Param{Key: "name", Value: "gopher"}}},
+		{"/src/some/file.png", true, "", nil},
+		{"/users", false, "/users", nil},
+		{"/h", false, "/h", nil},
+	})
+}
+
+// 参数约束 测试
+func TestTreeParamConstraint(t *testing.T) {
+	tree := &RadixTree{root: &node{}}
+
+	routes := [...]string{
+		"/user/:id<int>",
+		"/user/:id<int>/profile",
+		"/user/new",
+		"/doc/:id<uuid>",
+		"/doc/*path",
+		"/tag/:t<regex:[a-z]+(-[a-z]+)*>",
+		"/price/:p<float>/:cur<alpha>",
+	}
+	for _, route := range routes {
+		tree.addRoute(route, fakeHandler(route))
+	}
+
+	checkRequests(t, tree, testRequests{
+		{"/user/42", false, "/user/:id<int>", server2.Params{server2.Param{Key: "id", Value: "42"}}},
+		{"/user/42/profile", false, "/user/:id<int>/profile", server2.Params{server2.Param{Key: "id", Value: "42"}}},
+		{"/user/new", false, "/user/new", nil},
+		{"/user/abc", true, "", nil},
+		{"/user/abc/profile", true, "", nil},
+		{"/doc/123e4567-e89b-12d3-a456-426614174000", false, "/doc/:id<uuid>", server2.Params{server2.Param{Key: "id", Value: "123e4567-e89b-12d3-a456-426614174000"}}},
+		{"/doc/readme.md", false, "/doc/*path", server2.Params{server2.Param{Key: "path", Value: "readme.md"}}},
+		{"/tag/go-lang", false, "/tag/:t<regex:[a-z]+(-[a-z]+)*>", server2.Params{server2.Param{Key: "t", Value: "go-lang"}}},
+		{"/tag/Go", true, "", nil},
+		{"/price/9.5/usd", false, "/price/:p<float>/:cur<alpha>", server2.Params{server2.Param{Key: "p", Value: "9.5"}, server2.Param{Key: "cur", Value: "usd"}}},
+		{"/price/9.5/us1", true, "", nil},
+	})
+
+	// 大小写不敏感查找也要满足约束
+	out, found := tree.findCaseInsensitivePath("/USER/42/PROFILE", true)
+	assert.True(t, found)
+	assert.DeepEqual(t, "/user/42/profile", string(out))
+	_, found = tree.findCaseInsensitivePath("/USER/abc/PROFILE", true)
+	assert.False(t, found)
+
+	// 同一位置可以注册约束不同的参数
+	tree.addRoute("/user/:name", fakeHandler("/user/:name"))
+	tree.addRoute("/user/:id<uint>/posts", fakeHandler("/user/:id<uint>/posts"))
+	tree.addRoute("/user/:slug<alpha>/posts", fakeHandler("/user/:slug<alpha>/posts"))
+	checkNodes(t, tree.root)
+	checkRequests(t, tree, testRequests{
+		{"/user/42", false, "/user/:id<int>", server2.Params{server2.Param{Key: "id", Value: "42"}}},
+		{"/user/abc", false, "/user/:name", server2.Params{server2.Param{Key: "name", Value: "abc"}}},
+		// 先进入 :id<int> 节点 没有 /posts 后回溯到 :id<uint> 节点
+		{"/user/42/posts", false, "/user/:id<uint>/posts", server2.Params{server2.Param{Key: "id", Value: "42"}}},
+		{"/user/abc/posts", false, "/user/:slug<alpha>/posts", server2.Params{server2.Param{Key: "slug", Value: "abc"}}},
+		{"/user/a1/posts", true, "", nil},
+		{"/user/abc/profile", true, "", nil},
+	})
+	out, found = tree.findCaseInsensitivePath("/USER/abc/POSTS", true)
+	assert.True(t, found)
+	assert.DeepEqual(t, "/user/abc/posts", string(out))
+}
+
+// 不合法的参数约束 测试
+func TestTreeInvalidConstraint(t *testing.T) {
+	routes := [...]string{
+		"/user/:id<>",
+		"/user/:id<int",
+		"/user/:id<int><uint>",
+		"/user/:id<unknown>",
+		"/user/:<int>",
+		"/user/:id<regex:a/b>",
+		"/user/:id<regex:[>",
+	}
+	for _, route := range routes {
+		tree := &RadixTree{root: &node{}}
+		recv := catchPanic(func() {
+			tree.addRoute(route, fakeHandler(route))
+		})
+		if recv == nil {
+			t.Errorf("no panic while inserting route with invalid constraint '%s'", route)
+		}
+	}
+}
+
+// 同一位置约束不同的参数 测试
+func TestTreeSiblingConstraint(t *testing.T) {
+	tree := &RadixTree{root: &node{}}
+	routes := [...]string{
+		"/u/:id",
+		"/u/:id<int>",
+		"/u/:name<alpha>",
+		"/u/:id<int>/posts",
+		"/u/:name<alpha>/likes",
+		"/u/:any/likes",
+		"/f/:name.:ext<alpha>",
+		"/f/:name.:v<int>",
+	}
+	for _, route := range routes {
+		tree.addRoute(route, fakeHandler(route))
+	}
+	checkNodes(t, tree.root)
+
+	// 没有约束的参数节点最后尝试
+	checkRequests(t, tree, testRequests{
+		{"/u/42", false, "/u/:id<int>", server2.Params{server2.Param{Key: "id", Value: "42"}}},
+		{"/u/abc", false, "/u/:name<alpha>", server2.Params{server2.Param{Key: "name", Value: "abc"}}},
+		{"/u/a1", false, "/u/:id", server2.Params{server2.Param{Key: "id", Value: "a1"}}},
+		{"/u/42/posts", false, "/u/:id<int>/posts", server2.Params{server2.Param{Key: "id", Value: "42"}}},
+		{"/u/abc/likes", false, "/u/:name<alpha>/likes", server2.Params{server2.Param{Key: "name", Value: "abc"}}},
+		{"/u/42/likes", false, "/u/:any/likes", server2.Params{server2.Param{Key: "any", Value: "42"}}},
+		{"/u/abc/posts", true, "", nil},
+		{"/f/a.js", false, "/f/:name.:ext<alpha>", server2.Params{server2.Param{Key: "name", Value: "a"}, server2.Param{Key: "ext", Value: "js"}}},
+		{"/f/a.2", false, "/f/:name.:v<int>", server2.Params{server2.Param{Key: "name", Value: "a"}, server2.Param{Key: "v", Value: "2"}}},
+		{"/f/a.j2", true, "", nil},
+	})
+
+	// 约束相同的参数共用节点
+	err := tree.tryAddRoute("/u/:x<int>", fakeHandler("/u/:x<int>"), 0)
+	assert.True(t, errors.Is(err, ErrDuplicateRoute))
+	assert.DeepEqual(t, "/u/:id<int>", err.(*RouteError).Existing)
+
+	// 回溯时按照 path 中参数值的原始长度退回
+	tree.addRoute("/e/:a<alpha>/x", fakeHandler("/e/:a<alpha>/x"))
+	tree.addRoute("/e/:b/y", fakeHandler("/e/:b/y"))
+	checkRequests(t, tree, testRequests{
+		{"/e/%41bc/y", false, "/e/:b/y", server2.Params{server2.Param{Key: "b", Value: "Abc"}}},
+	}, true)
+
+	// 删除有约束的参数节点后 回到没有约束的参数节点
+	tree.remove("/u/:name<alpha>")
+	tree.remove("/u/:name<alpha>/likes")
+	checkNodes(t, tree.root)
+	checkRequests(t, tree, testRequests{
+		{"/u/abc", false, "/u/:id", server2.Params{server2.Param{Key: "id", Value: "abc"}}},
+		{"/u/abc/likes", false, "/u/:any/likes", server2.Params{server2.Param{Key: "any", Value: "abc"}}},
+		{"/u/42", false, "/u/:id<int>", server2.Params{server2.Param{Key: "id", Value: "42"}}},
+	})
+}
+
+// 可选参数 测试
+func TestTreeOptionalParam(t *testing.T) {
+	tree := &RadixTree{root: &node{}}
+
+	routes := [...]string{
+		"/user/:name?",
+		"/cmd/:tool/:sub?",
+		"/page_:n<int>?",
+		"/:lang?",
+	}
+	for _, route := range routes {
+		tree.addRoute(route, fakeHandler(route))
+	}
+
+	checkRequests(t, tree, testRequests{
+		{"/user/gopher", false, "/user/:name?", server2.Params{server2.Param{Key: "name", Value: "gopher"}}},
+		{"/user", false, "/user/:name?", nil},
+		{"/cmd/test/3", false, "/cmd/:tool/:sub?", server2.Params{server2.Param{Key: "tool", Value: "test"}, server2.Param{Key: "sub", Value: "3"}}},
+		{"/cmd/test", false, "/cmd/:tool/:sub?", server2.Params{server2.Param{Key: "tool", Value: "test"}}},
+		{"/page_2", false, "/page_:n<int>?", server2.Params{server2.Param{Key: "n", Value: "2"}}},
+		{"/page_", false, "/page_:n<int>?", nil},
+		{"/page_x", false, "/:lang?", server2.Params{server2.Param{Key: "lang", Value: "page_x"}}},
+		{"/en", false, "/:lang?", server2.Params{server2.Param{Key: "lang", Value: "en"}}},
+		{"/", false, "/:lang?", nil},
+	})
+
+	// 缺省的参数不存在
+	ps := getParams()
+	value := tree.find("/user", ps, false)
+	assert.DeepEqual(t, "/user/:name?", value.fullPath)
+	_, ok := ps.Get("name")
+	assert.False(t, ok)
+
+	// 两种形式都会与已注册的路由冲突
+	for _, route := range [...]string{"/user", "/user/:name", "/cmd/:tool"} {
+		recv := catchPanic(func() {
+			tree.addRoute(route, fakeHandler(route))
+		})
+		assert.True(t, recv != nil)
+	}
+
+	// 两种形式一起删除
+	assert.True(t, tree.remove("/user/:name?"))
+	assert.False(t, tree.remove("/user/:name?"))
+	checkNodes(t, tree.root)
+	checkRequests(t, tree, testRequests{
+		{"/user/gopher", true, "", nil},
+		{"/user", false, "/:lang?", server2.Params{server2.Param{Key: "lang", Value: "user"}}},
+		{"/cmd/test", false, "/cmd/:tool/:sub?", nil},
+	})
+}
+
+// 不合法的可选参数 测试
+func TestTreeInvalidOptionalParam(t *testing.T) {
+	routes := [...]string{
+		"/user/:name?/posts",
+		"/user/:name??",
+		"/user?",
+		"/user/:?",
+		"/src/*filepath?",
+		"/user/:id<int>?x",
+	}
+	for _, route := range routes {
+		tree := &RadixTree{root: &node{}}
+		recv := catchPanic(func() {
+			tree.addRoute(route, fakeHandler(route))
+		})
+		if recv == nil {
+			t.Errorf("no panic while inserting route with invalid optional wildcard '%s'", route)
+		}
+	}
+}
+
+// 同一路径段中的多个参数 测试
+func TestTreeMultiParamSegment(t *testing.T) {
+	tree := &RadixTree{root: &node{}}
+
+	routes := [...]string{
+		"/files/:name.:ext",
+		"/files/:name",
+		"/range/:from<int>-:to<int>",
+		"/range/:from<int>",
+		"/v/:major.:minor.:patch",
+		"/img/:name-:w<uint>x:h<uint>.png",
+		"/img/:name-:w<uint>x:h<uint>.jpg",
+		// 路径段中只有一个参数时 参数名到 / 为止
+		"/u/:user-id",
+		"/u/:user-id/:名字",
+		"/cn/:姓-:名",
+	}
+	for _, route := range routes {
+		tree.addRoute(route, fakeHandler(route))
+	}
+	checkNodes(t, tree.root)
+
+	checkRequests(t, tree, testRequests{
+		{"/files/a.txt", false, "/files/:name.:ext", server2.Params{server2.Param{Key: "name", Value: "a"}, server2.Param{Key: "ext", Value: "txt"}}},
+		// 参数值到第一个分隔符为止，剩余部分属于下一个参数
+		{"/files/a.tar.gz", false, "/files/:name.:ext", server2.Params{server2.Param{Key: "name", Value: "a"}, server2.Param{Key: "ext", Value: "tar.gz"}}},
+		// 没有分隔符时整个路径段都是参数值
+		{"/files/readme", false, "/files/:name", server2.Params{server2.Param{Key: "name", Value: "readme"}}},
+		// 分隔符不能作为参数值的开头
+		{"/files/.env", false, "/files/:name", server2.Params{server2.Param{Key: "name", Value: ".env"}}},
+		// 分隔符之后没有内容时 不会退回到更长的参数值
+		{"/files/a.", true, "", nil},
+		{"/range/1-10", false, "/range/:from<int>-:to<int>", server2.Params{server2.Param{Key: "from", Value: "1"}, server2.Param{Key: "to", Value: "10"}}},
+		{"/range/7", false, "/range/:from<int>", server2.Params{server2.Param{Key: "from", Value: "7"}}},
+		{"/range/1-x", true, "", nil},
+		{"/v/1.2.3", false, "/v/:major.:minor.:patch", server2.Params{server2.Param{Key: "major", Value: "1"}, server2.Param{Key: "minor", Value: "2"}, server2.Param{Key: "patch", Value: "3"}}},
+		{"/v/1.2", true, "", nil},
+		{"/img/cat-640x480.png", false, "/img/:name-:w<uint>x:h<uint>.png", server2.Params{server2.Param{Key: "name", Value: "cat"}, server2.Param{Key: "w", Value: "640"}, server2.Param{Key: "h", Value: "480"}}},
+		{"/img/cat-640x480.jpg", false, "/img/:name-:w<uint>x:h<uint>.jpg", server2.Params{server2.Param{Key: "name", Value: "cat"}, server2.Param{Key: "w", Value: "640"}, server2.Param{Key: "h", Value: "480"}}},
+		{"/img/cat-640x480.gif", true, "", nil},
+		{"/u/42", false, "/u/:user-id", server2.Params{server2.Param{Key: "user-id", Value: "42"}}},
+		{"/u/42-x", false, "/u/:user-id", server2.Params{server2.Param{Key: "user-id", Value: "42-x"}}},
+		{"/u/42/gopher", false, "/u/:user-id/:名字", server2.Params{server2.Param{Key: "user-id", Value: "42"}, server2.Param{Key: "名字", Value: "gopher"}}},
+		{"/cn/张-三", false, "/cn/:姓-:名", server2.Params{server2.Param{Key: "姓", Value: "张"}, server2.Param{Key: "名", Value: "三"}}},
+	})
+
+	out, found := tree.findCaseInsensitivePath("/IMG/cat-640x480.PNG", false)
+	assert.True(t, found)
+	assert.DeepEqual(t, "/img/cat-640x480.png", out)
+
+	// 参数之间必须存在分隔符
+	for _, route := range [...]string{"/:from:to", "/x/:a-:b:c", "/x/:a-*b"} {
+		recv := catchPanic(func() {
+			tree.addRoute(route, fakeHandler(route))
+		})
+		assert.True(t, recv != nil)
+	}
+
+	assert.True(t, tree.remove("/files/:name.:ext"))
+	checkNodes(t, tree.root)
+	checkRequests(t, tree, testRequests{
+		{"/files/a.txt", false, "/files/:name", server2.Params{server2.Param{Key: "name", Value: "a.txt"}}},
+	})
+}
@@ -0,0 +1,86 @@
+package route
+
+import (
+	"context"
+	"errors"
+	"github.com/Yuki-J1/wailsrouter/pkg/app/server"
+	"net/http"
+	"reflect"
+)
+
+// StatusCoder 可以由 Typed 业务函数返回的错误实现，用于指定响应的状态码
+// 被包装的错误同样生效，小于 400 的状态码会被忽略
+type StatusCoder interface {
+	StatusCode() int
+}
+
+// Typed 将 fn 适配为普通的 server.HandlerFunc，可以和中间件一起组成调用链
+//
+//	engine.Handle("/user/:id", auth, route.Typed(func(c context.Context, req GetUserReq) (*User, error) { ... }))
+//
+// Req 为结构体或结构体指针时通过 ctx.Bind 绑定路由参数、查询参数和请求体，其他类型只从请求体解码
+// fn 成功时通过 ctx.Render 写入 Resp，状态码 200
+// 绑定失败时状态码为 400，fn 返回错误时状态码为 500 或错误实现的 StatusCoder
+// 失败时中止调用链，设置状态码并将错误记录到 ctx.Errors，响应体由 Engine.ErrorHandler 渲染
+// Engine.ErrorHandler 为 nil 时将 AsError 转换后的 *Error 写入响应体
+func Typed[Req, Resp any](fn func(c context.Context, req Req) (Resp, error)) server.HandlerFunc {
+	return func(c context.Context, ctx *server.RequestContext) {
+		var req Req
+		if err := bindTyped(ctx, &req); err != nil {
+			abortTyped(ctx, http.StatusBadRequest, err)
+			return
+		}
+		resp, err := fn(c, req)
+		if err != nil {
+			code := http.StatusInternalServerError
+			var sc StatusCoder
+			if errors.As(err, &sc) && sc.StatusCode() >= 400 {
+				code = sc.StatusCode()
+			}
+			abortTyped(ctx, code, err)
+			return
+		}
+		ctx.Render(http.StatusOK, resp)
+	}
+}
+
+// bindTyped 将请求绑定到 req
+func bindTyped(ctx *server.RequestContext, req interface{}) error {
+	v := reflect.ValueOf(req).Elem()
+	switch {
+	case v.Kind() == reflect.Struct:
+		return ctx.Bind(req)
+	case v.Kind() == reflect.Pointer && v.Type().Elem().Kind() == reflect.Struct:
+		v.Set(reflect.New(v.Type().Elem()))
+		return ctx.Bind(v.Interface())
+	}
+	return ctx.BindBody(req)
+}
+
+// typedError Typed 记录到 ctx.Errors 中的错误，用于在没有 ErrorHandler 时由 Engine 写入响应体
+// errors.Is 和 errors.As 可以获取 fn 返回的原始错误
+type typedError struct {
+	err error
+}
+
+func (e *typedError) Error() string {
+	return e.err.Error()
+}
+
+func (e *typedError) Unwrap() error {
+	return e.err
+}
+
+// abortTyped 中止调用链，设置状态码并记录错误，不写入响应体
+func abortTyped(ctx *server.RequestContext, code int, err error) {
+	ctx.AbortWithStatus(code)
+	ctx.Error(&typedError{err: err})
+}
+
+// renderTyped 最后记录的错误来自 Typed 时，将 AsError 转换后的 *Error 写入响应体
+func renderTyped(ctx *server.RequestContext) {
+	var te *typedError
+	if errors.As(ctx.Errors.Last(), &te) {
+		ctx.Render(ctx.Response.StatusCode, AsError(te.err, ctx.Response.StatusCode))
+	}
+}
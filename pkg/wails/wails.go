@@ -0,0 +1,88 @@
+package wails
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/Yuki-J1/wailsrouter/pkg/app/server"
+	"github.com/Yuki-J1/wailsrouter/pkg/route"
+)
+
+// ErrEmptyPath Invoke 传入的 path 为空
+var ErrEmptyPath = errors.New("wails: path should not be empty")
+
+// App 将 route.Engine 适配为可以通过 Wails 绑定给前端调用的结构体
+//
+//	app := wails.New(engine)
+//	wails.Run(&options.App{
+//		OnStartup: app.Startup,
+//		Bind:      []interface{}{app},
+//	})
+type App struct {
+	ctx    context.Context // Wails 运行时 context
+	engine *route.Engine
+}
+
+// Response Invoke 返回给前端的结果
+type Response struct {
+	Code  int         `json:"code"`            // handler 设置的状态码
+	Data  interface{} `json:"data,omitempty"`  // handler 写入的响应体
+	Error string      `json:"error,omitempty"` // handler 报告的错误
+
+	Location string   `json:"location,omitempty"` // 路由重定向时的规范路径
+	Allow    []string `json:"allow,omitempty"`    // 操作类型不允许时，该 path 允许的操作类型
+}
+
+func New(engine *route.Engine) *App {
+	return &App{
+		ctx:    context.Background(),
+		engine: engine,
+	}
+}
+
+// Startup 保存 Wails 运行时 context，作为 OnStartup 回调使用
+// 保存的 context 会作为 handler 的第一个参数传入
+func (a *App) Startup(ctx context.Context) {
+	a.ctx = ctx
+}
+
+// Invoke 前端通过 Wails 绑定调用的入口
+// 根据 path 在 Engine 中以默认的操作类型查找路由，payload 作为请求体交给 handler，返回 handler 写入的结果
+func (a *App) Invoke(path string, payload json.RawMessage) (Response, error) {
+	return a.InvokeKind("", path, payload)
+}
+
+// InvokeKind 与 Invoke 相同，但以指定的操作类型查找路由，例如 route.KindCommand
+func (a *App) InvokeKind(kind, path string, payload json.RawMessage) (Response, error) {
+	if path == "" {
+		return Response{}, ErrEmptyPath
+	}
+
+	resp := a.engine.ServeRequest(a.ctx, path, server.Request{
+		Kind:        kind,
+		Body:        payload,
+		ContentType: server.MIMEApplicationJSON,
+	})
+	return newResponse(&resp), nil
+}
+
+// newResponse 根据响应体的编码类型转换为前端可以直接使用的结果
+// JSON 原样返回，文本转为字符串，其他二进制数据由 Wails 编码为 base64
+func newResponse(resp *server.Response) Response {
+	res := Response{Code: resp.StatusCode, Location: resp.Location, Allow: resp.Allow}
+	if resp.Err != nil {
+		res.Error = resp.Err.Error()
+	}
+	if len(resp.Body) == 0 {
+		return res
+	}
+	switch {
+	case resp.IsJSON():
+		res.Data = json.RawMessage(resp.Body)
+	case resp.IsText():
+		res.Data = string(resp.Body)
+	default:
+		res.Data = resp.Body
+	}
+	return res
+}
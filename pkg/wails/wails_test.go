@@ -0,0 +1,94 @@
+package wails
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/Yuki-J1/wailsrouter/pkg/app/server"
+	"github.com/Yuki-J1/wailsrouter/pkg/route"
+	"github.com/cloudwego/hertz/pkg/common/test/assert"
+	"net/http"
+	"testing"
+)
+
+func TestApp_Invoke(t *testing.T) {
+	engine := route.NewEngine()
+	engine.Handle("/echo/:name", func(c context.Context, ctx *server.RequestContext) {
+		var req map[string]string
+		if err := json.Unmarshal(ctx.Request.Body, &req); err != nil {
+			t.Fatal(err)
+		}
+		req["name"] = ctx.Params.ByName("name")
+		ctx.JSON(http.StatusOK, req)
+	})
+
+	app := New(engine)
+	resp, err := app.Invoke("/echo/YKJ", json.RawMessage(`{"msg":"hi"}`))
+	assert.Nil(t, err)
+	assert.DeepEqual(t, http.StatusOK, resp.Code)
+	assert.DeepEqual(t, json.RawMessage(`{"msg":"hi","name":"YKJ"}`), resp.Data)
+
+	_, err = app.Invoke("", nil)
+	assert.DeepEqual(t, ErrEmptyPath, err)
+}
+
+func TestApp_Startup(t *testing.T) {
+	type ctxKey struct{}
+	engine := route.NewEngine()
+	var got interface{}
+	engine.Handle("/ctx", func(c context.Context, ctx *server.RequestContext) {
+		got = c.Value(ctxKey{})
+	})
+
+	app := New(engine)
+	app.Startup(context.WithValue(context.Background(), ctxKey{}, "wails"))
+	_, err := app.Invoke("/ctx", nil)
+	assert.Nil(t, err)
+	assert.DeepEqual(t, "wails", got)
+}
+
+func TestApp_InvokeResponse(t *testing.T) {
+	engine := route.NewEngine()
+	engine.Handle("/text", func(c context.Context, ctx *server.RequestContext) {
+		ctx.String(http.StatusOK, "hello %s", "wails")
+	})
+	engine.Handle("/data", func(c context.Context, ctx *server.RequestContext) {
+		ctx.Data(http.StatusOK, server.MIMEOctetStream, []byte{0x1, 0x2})
+	})
+	engine.Handle("/error", func(c context.Context, ctx *server.RequestContext) {
+		ctx.Status(http.StatusBadRequest)
+		ctx.Error(errors.New("bad payload"))
+	})
+	engine.Handle("/empty", func(c context.Context, ctx *server.RequestContext) {})
+
+	app := New(engine)
+	resp, _ := app.Invoke("/text", nil)
+	assert.DeepEqual(t, Response{Code: http.StatusOK, Data: "hello wails"}, resp)
+
+	resp, _ = app.Invoke("/data", nil)
+	assert.DeepEqual(t, Response{Code: http.StatusOK, Data: []byte{0x1, 0x2}}, resp)
+
+	resp, _ = app.Invoke("/error", nil)
+	assert.DeepEqual(t, Response{Code: http.StatusBadRequest, Error: "bad payload"}, resp)
+
+	resp, _ = app.Invoke("/empty", nil)
+	assert.DeepEqual(t, Response{Code: http.StatusOK}, resp)
+}
+
+func TestApp_InvokeKind(t *testing.T) {
+	engine := route.NewEngine()
+	engine.HandleKindNotAllowed = true
+	engine.Command("/user/:name", func(c context.Context, ctx *server.RequestContext) {
+		ctx.String(http.StatusOK, "updated %s", ctx.Params.ByName("name"))
+	})
+
+	app := New(engine)
+	resp, err := app.InvokeKind(route.KindCommand, "/user/YKJ", nil)
+	assert.Nil(t, err)
+	assert.DeepEqual(t, Response{Code: http.StatusOK, Data: "updated YKJ"}, resp)
+
+	resp, err = app.Invoke("/user/YKJ", nil)
+	assert.Nil(t, err)
+	assert.DeepEqual(t, http.StatusMethodNotAllowed, resp.Code)
+	assert.DeepEqual(t, []string{route.KindCommand}, resp.Allow)
+}